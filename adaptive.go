@@ -0,0 +1,110 @@
+package lokilogger
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultMinBatchSize     = 10
+	defaultMaxBatchSize     = 1000
+	defaultMinFlushInterval = 500 * time.Millisecond
+	defaultMaxFlushInterval = 10 * time.Second
+
+	// adaptInterval is the period the throughput is measured over.
+	adaptInterval = time.Second
+)
+
+// AdaptiveBatching bounds the batch size and flush interval adapted to the throughput, see
+// Config.AdaptiveBatching.
+type AdaptiveBatching struct {
+	MinBatchSize     int           // 10 by default.
+	MaxBatchSize     int           // 1000 by default.
+	MinFlushInterval time.Duration // 500ms by default.
+	MaxFlushInterval time.Duration // 10s by default.
+}
+
+// setDefaults replaces zero values with their defaults.
+func (a *AdaptiveBatching) setDefaults() {
+	if a.MinBatchSize == 0 {
+		a.MinBatchSize = defaultMinBatchSize
+	}
+	if a.MaxBatchSize == 0 {
+		a.MaxBatchSize = max(defaultMaxBatchSize, a.MinBatchSize)
+	}
+	if a.MinFlushInterval == 0 {
+		a.MinFlushInterval = defaultMinFlushInterval
+	}
+	if a.MaxFlushInterval == 0 {
+		a.MaxFlushInterval = max(defaultMaxFlushInterval, a.MinFlushInterval)
+	}
+}
+
+// validate reports the first invalid bound.
+func (a *AdaptiveBatching) validate() error {
+	if a.MinBatchSize < 1 || a.MaxBatchSize < a.MinBatchSize {
+		return fmt.Errorf("invalid AdaptiveBatching batch sizes %d to %d: must be positive and ordered", a.MinBatchSize, a.MaxBatchSize)
+	}
+	if a.MinFlushInterval <= 0 || a.MaxFlushInterval < a.MinFlushInterval {
+		return fmt.Errorf("invalid AdaptiveBatching flush intervals %s to %s: must be positive and ordered", a.MinFlushInterval, a.MaxFlushInterval)
+	}
+	return nil
+}
+
+// adapt returns the batch size and flush interval for the rate of entries per second. The batch size
+// collects about a second of entries, and the flush interval grows with it from its minimum to its maximum.
+func (a *AdaptiveBatching) adapt(rate float64) (int, time.Duration) {
+	size := int(min(max(rate, float64(a.MinBatchSize)), float64(a.MaxBatchSize)))
+	if a.MaxBatchSize == a.MinBatchSize {
+		return size, a.MinFlushInterval
+	}
+
+	load := float64(size-a.MinBatchSize) / float64(a.MaxBatchSize-a.MinBatchSize)
+	return size, a.MinFlushInterval + time.Duration(load*float64(a.MaxFlushInterval-a.MinFlushInterval))
+}
+
+// startAdapting starts adapting the batching to the throughput unless it is adapted already.
+func (l *LokiLogger) startAdapting() {
+	if l.adapting.CompareAndSwap(false, true) {
+		go l.adaptLoop()
+	}
+}
+
+// adaptLoop measures the throughput every second and adapts BatchSize and FlushInterval to it until the
+// logger stops. It idles while AdaptiveBatching is unset.
+func (l *LokiLogger) adaptLoop() {
+	ticker := time.NewTicker(adaptInterval)
+	defer ticker.Stop()
+
+	last, lastAt := l.counters.received.Load(), time.Now()
+	var rate float64
+	for {
+		var now time.Time
+		select {
+		case <-l.ctx.Done():
+			return
+		case now = <-ticker.C:
+		}
+
+		// The rate is smoothed, so that a single burst doesn't swing the batching.
+		n := l.counters.received.Load()
+		rate = rate/2 + float64(n-last)/now.Sub(lastAt).Seconds()/2
+		last, lastAt = n, now
+
+		cfg := l.config()
+		if cfg.AdaptiveBatching == nil {
+			continue
+		}
+		size, interval := cfg.AdaptiveBatching.adapt(rate)
+		if size == cfg.BatchSize && interval == cfg.FlushInterval {
+			continue
+		}
+
+		// A configuration replaced by UpdateConfig meanwhile is adapted on the next tick.
+		c := *cfg
+		c.BatchSize, c.FlushInterval = size, interval
+		if l.cfg.CompareAndSwap(cfg, &c) {
+			l.debugf("Adapted batching to %.0f entries/s: BatchSize %d, FlushInterval %s", rate, size, interval)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package lokilogger
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveBatchingAdapt(t *testing.T) {
+	a := AdaptiveBatching{MinBatchSize: 10, MaxBatchSize: 110, MinFlushInterval: time.Second, MaxFlushInterval: 11 * time.Second}
+
+	for _, tt := range []struct {
+		rate     float64
+		size     int
+		interval time.Duration
+	}{
+		{0, 10, time.Second},
+		{60, 60, 6 * time.Second},
+		{110, 110, 11 * time.Second},
+		{5000, 110, 11 * time.Second},
+	} {
+		if size, interval := a.adapt(tt.rate); size != tt.size || interval != tt.interval {
+			t.Errorf("adapt(%v) = %d, %s, want %d, %s", tt.rate, size, interval, tt.size, tt.interval)
+		}
+	}
+}
+
+func TestAdaptiveBatchingDefaults(t *testing.T) {
+	cfg := Config{AdaptiveBatching: &AdaptiveBatching{MinBatchSize: 2000}}
+	cfg.setDefaults()
+
+	a := cfg.AdaptiveBatching
+	if a.MaxBatchSize != 2000 || a.MinFlushInterval != defaultMinFlushInterval || a.MaxFlushInterval != defaultMaxFlushInterval {
+		t.Errorf("got %+v", *a)
+	}
+	if cfg.BatchSize != 2000 || cfg.FlushInterval != defaultMinFlushInterval || cfg.MaxQueueSize < 2000 {
+		t.Errorf("got BatchSize %d, FlushInterval %s and MaxQueueSize %d", cfg.BatchSize, cfg.FlushInterval, cfg.MaxQueueSize)
+	}
+	if err := a.validate(); err != nil {
+		t.Error(err)
+	}
+
+	invalid := AdaptiveBatching{MinBatchSize: 10, MaxBatchSize: 5, MinFlushInterval: time.Second, MaxFlushInterval: time.Second}
+	if err := invalid.validate(); err == nil {
+		t.Error("unordered batch sizes accepted")
+	}
+}
+
+func TestAdaptiveBatchingFollowsThroughput(t *testing.T) {
+	l := newTestLogger(t, context.Background(), Config{Sink: nopSink{}, AdaptiveBatching: &AdaptiveBatching{}})
+	defer l.Close(context.Background())
+
+	if c := l.config(); c.BatchSize != defaultMinBatchSize {
+		t.Fatalf("started with BatchSize %d, want %d", c.BatchSize, defaultMinBatchSize)
+	}
+
+	// About 800 entries per second, smoothed to at least 400 by the first tick. The loop starts measuring
+	// once it runs.
+	time.Sleep(100 * time.Millisecond)
+	for range 800 {
+		l.counters.received.Add(1)
+	}
+	time.Sleep(adaptInterval)
+
+	if c := l.config(); c.BatchSize < 300 || c.FlushInterval <= defaultMinFlushInterval {
+		t.Errorf("got BatchSize %d and FlushInterval %s after a burst", c.BatchSize, c.FlushInterval)
+	}
+}
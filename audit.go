@@ -0,0 +1,359 @@
+package lokilogger
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAuditClosed is returned for entries logged after the AuditLogger was shut down.
+var ErrAuditClosed = errors.New("audit logger is shut down")
+
+// auditRecord is a line of the audit write-ahead log.
+type auditRecord struct {
+	Seq   uint64 `json:"seq"`
+	Entry Entry  `json:"entry"`
+}
+
+// AuditLogger ships entries with strict delivery guarantees, e.g. for audit trails that must not lose a
+// record. Every entry is appended to a write-ahead log in the directory and synced to disk before Append
+// or Log returns, so an entry that can't be durably queued fails the call instead of being dropped. The
+// entries are pushed in order, one batch at a time, and retryable failures are retried until they
+// succeed; entries the primary sink permanently rejects go to DeadLetterFile, or are retried as well
+// without one. The pipeline, middlewares, sampling, deduplication, MinLevel and rate limits of the
+// configuration don't apply. Entries not acknowledged before a crash or Shutdown are pushed again on the
+// next start, so delivery is at least once.
+type AuditLogger struct {
+	l       *LokiLogger
+	cancel  context.CancelFunc
+	ackPath string
+
+	mu      sync.Mutex
+	wal     *os.File
+	seq     uint64        // Sequence of the last appended entry.
+	acked   uint64        // Sequence of the last entry acknowledged by the sink.
+	pending []auditRecord // Appended entries not acknowledged yet, in order.
+	closed  bool
+	acks    chan struct{} // Closed and replaced whenever entries are acknowledged.
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewAuditLogger opens the write-ahead log in dir, creating it if needed, and starts pushing the entries
+// left unacknowledged by a previous run. The remaining configuration is the one of Init.
+func NewAuditLogger(ctx context.Context, dir string, cfg Config) (*AuditLogger, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("audit dir: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	l, err := newLogger(ctx, cfg)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	a := &AuditLogger{
+		l:       l,
+		cancel:  cancel,
+		ackPath: filepath.Join(dir, "audit.ack"),
+		acks:    make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := a.open(filepath.Join(dir, "audit.wal")); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go a.sender()
+
+	return a, nil
+}
+
+// open reads the acknowledged sequence and the unacknowledged entries of the write-ahead log.
+func (a *AuditLogger) open(path string) error {
+	if data, err := os.ReadFile(a.ackPath); err == nil {
+		if a.acked, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err != nil {
+			return fmt.Errorf("audit ack file: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	a.seq = a.acked
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+
+	// A record without its newline was cut off by a crash before it was synced and acknowledged to the caller.
+	var valid int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		var rec auditRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			break
+		}
+		valid += int64(len(line))
+		if rec.Seq > a.acked {
+			a.pending = append(a.pending, rec)
+			a.seq = rec.Seq
+		}
+	}
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	a.wal = f
+	return nil
+}
+
+// Append durably queues the entry and returns once it is synced to disk, without waiting for the sink to
+// acknowledge it. An error means the entry was not queued.
+func (a *AuditLogger) Append(e Entry) error {
+	_, err := a.append(e)
+	return err
+}
+
+// Log durably queues the entry and waits until the sink acknowledged it or ctx is done. After an error
+// wrapping ctx.Err() the entry is still queued and will be delivered.
+func (a *AuditLogger) Log(ctx context.Context, e Entry) error {
+	seq, err := a.append(e)
+	if err != nil {
+		return err
+	}
+
+	for {
+		a.mu.Lock()
+		acked, acks := a.acked, a.acks
+		a.mu.Unlock()
+		if acked >= seq {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("audit entry %d queued but not acknowledged: %w", seq, ctx.Err())
+		case <-acks:
+		}
+	}
+}
+
+// append writes the entry to the write-ahead log and returns its sequence.
+func (a *AuditLogger) append(e Entry) (uint64, error) {
+	cfg := a.l.config()
+	if e.Time.IsZero() {
+		e.Time = cfg.Clock.Now()
+	}
+	e.Level = normalizeLevel(cfg, cmp.Or(e.Level, cfg.DefaultLevel, "info"))
+	e = sanitizeLabels(cfg, extractTenant(e))
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return 0, ErrAuditClosed
+	}
+
+	rec := auditRecord{Seq: a.seq + 1, Entry: e}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return 0, err
+	}
+	off, err := a.wal.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, fmt.Errorf("audit write-ahead log: %w", err)
+	}
+	if _, err := a.wal.Write(append(data, '\n')); err != nil {
+		a.rollback(off)
+		return 0, fmt.Errorf("audit write-ahead log: %w", err)
+	}
+	if err := a.wal.Sync(); err != nil {
+		a.rollback(off)
+		return 0, fmt.Errorf("audit write-ahead log: %w", err)
+	}
+
+	a.seq = rec.Seq
+	a.pending = append(a.pending, rec)
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+
+	return rec.Seq, nil
+}
+
+// rollback removes a partially written record from the end of the write-ahead log, which ended at off.
+func (a *AuditLogger) rollback(off int64) {
+	a.wal.Truncate(off)
+	a.wal.Seek(off, io.SeekStart)
+}
+
+// sender pushes the pending entries in order until the logger is shut down.
+func (a *AuditLogger) sender() {
+	defer close(a.done)
+
+	for {
+		cfg := a.l.config()
+
+		a.mu.Lock()
+		batch := a.pending[:min(len(a.pending), cfg.BatchSize)]
+		a.mu.Unlock()
+
+		if len(batch) == 0 {
+			select {
+			case <-a.stop:
+				return
+			case <-a.wake:
+			}
+			continue
+		}
+
+		if !a.push(batch) {
+			return
+		}
+		a.ack(batch[len(batch)-1].Seq, len(batch))
+	}
+}
+
+// push sends the records to the primary sink, retrying until they are stored, dead-lettered or the
+// logger is shut down. It reports whether the records were delivered.
+func (a *AuditLogger) push(batch []auditRecord) bool {
+	l := a.l
+	cfg := l.config()
+
+	entries := make([]Entry, len(batch))
+	for i, rec := range batch {
+		entries[i] = rec.Entry
+	}
+
+	l.mu.Lock()
+	sink := l.sinks[0]
+	l.mu.Unlock()
+
+	l.counters.received.Add(int64(len(entries)))
+	for _, streams := range splitTenants(l.groupStreams(cfg, l.labels, entries)) {
+		for attempt := 1; ; attempt++ {
+			ctx, cancel := l.pushContext(l.shipping(), cfg)
+			err := classify(pushSafely(ctx, sink, streams))
+			cancel()
+			if err == nil {
+				l.counters.success(countEntries(streams))
+				break
+			}
+
+			l.counters.failure(err)
+			l.onError(sink, streams, attempt, false, err)
+			if !retryable(err) && l.deadLetter(streams, err) {
+				l.counters.failed.Add(int64(countEntries(streams)))
+				l.logf("error", "Error loki audit push to %T, batch written to %s: %v", sink, cfg.DeadLetterFile, err)
+				break
+			}
+
+			l.logf("warn", "Audit push attempt %d failed: %v", attempt, err)
+			select {
+			case <-a.stop:
+				return false
+			case <-time.After(min(time.Second*time.Duration(attempt), maxStrictBackoff)):
+			}
+		}
+	}
+
+	return true
+}
+
+// ack records the entries up to seq as acknowledged and compacts the write-ahead log once none is pending.
+func (a *AuditLogger) ack(seq uint64, n int) {
+	// Entries acknowledged but not recorded yet are pushed again after a crash.
+	if err := writeFileAtomic(a.ackPath, []byte(strconv.FormatUint(seq, 10))); err != nil {
+		a.l.logf("error", "Error loki audit ack file: %v", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.acked = seq
+	a.pending = a.pending[n:]
+	close(a.acks)
+	a.acks = make(chan struct{})
+
+	if len(a.pending) == 0 {
+		if err := a.wal.Truncate(0); err == nil {
+			a.wal.Seek(0, io.SeekStart)
+		}
+	}
+}
+
+// Shutdown stops accepting entries and waits until the queued entries are acknowledged or ctx is done.
+// The entries still pending then stay in the write-ahead log for the next start.
+func (a *AuditLogger) Shutdown(ctx context.Context) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	seq := a.seq
+	a.mu.Unlock()
+
+	var err error
+	for err == nil {
+		a.mu.Lock()
+		acked, acks := a.acked, a.acks
+		a.mu.Unlock()
+		if acked >= seq {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-acks:
+		}
+	}
+
+	if err != nil {
+		a.l.abortPushes()
+	}
+	close(a.stop)
+	<-a.done
+	a.cancel()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if cerr := a.wal.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeFileAtomic replaces the file at path with data.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,146 @@
+package lokilogger
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultResolveInterval = 30 * time.Second
+
+// balancedHost holds the resolved addresses of a host and a transport per address.
+type balancedHost struct {
+	addrs      []string
+	transports map[string]*http.Transport
+	next       int
+	resolvedAt time.Time
+}
+
+// BalancingTransport is an http.RoundTripper spreading requests across all IP
+// addresses a host resolves to, e.g. the pods behind a headless Kubernetes
+// service. Addresses are re-resolved every interval; each address keeps its
+// own connection pool, while the URL, Host header and TLS server name stay
+// unchanged.
+type BalancingTransport struct {
+	base     *http.Transport
+	interval time.Duration
+	resolver *net.Resolver
+
+	mu    sync.Mutex
+	hosts map[string]*balancedHost
+}
+
+// NewBalancingTransport returns a BalancingTransport creating its per-address
+// transports from base. Zero interval re-resolves every 30 seconds.
+func NewBalancingTransport(base *http.Transport, interval time.Duration) *BalancingTransport {
+	if interval <= 0 {
+		interval = defaultResolveInterval
+	}
+
+	return &BalancingTransport{
+		base:     base,
+		interval: interval,
+		resolver: net.DefaultResolver,
+		hosts:    make(map[string]*balancedHost),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *BalancingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt := t.pick(req); rt != nil {
+		return rt.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// pick returns the transport of the next address of the request host, or nil if it can't be resolved.
+func (t *BalancingTransport) pick(req *http.Request) *http.Transport {
+	host, port := req.URL.Hostname(), req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := net.JoinHostPort(host, port)
+	h := t.hosts[key]
+	if h == nil || time.Since(h.resolvedAt) >= t.interval {
+		h = t.resolve(req.Context(), host, port, h)
+		if h == nil {
+			return nil
+		}
+		t.hosts[key] = h
+	}
+
+	addr := h.addrs[h.next%len(h.addrs)]
+	h.next++
+
+	return h.transports[addr]
+}
+
+// resolve looks up the host and returns its addresses, reusing the transports of addresses that are still present.
+// The previous state is kept if the lookup fails.
+func (t *BalancingTransport) resolve(ctx context.Context, host, port string, prev *balancedHost) *balancedHost {
+	ips, err := t.resolver.LookupHost(ctx, host)
+	if err != nil || len(ips) == 0 {
+		if prev != nil {
+			prev.resolvedAt = time.Now()
+		}
+		return prev
+	}
+
+	h := &balancedHost{transports: make(map[string]*http.Transport, len(ips)), resolvedAt: time.Now()}
+	if prev != nil {
+		h.next = prev.next
+	}
+
+	for _, ip := range ips {
+		addr := net.JoinHostPort(ip, port)
+		h.addrs = append(h.addrs, addr)
+		if prev != nil && prev.transports[addr] != nil {
+			h.transports[addr] = prev.transports[addr]
+			delete(prev.transports, addr)
+			continue
+		}
+		h.transports[addr] = t.transportFor(addr)
+	}
+
+	// Release the connections of addresses that disappeared.
+	if prev != nil {
+		for _, tr := range prev.transports {
+			tr.CloseIdleConnections()
+		}
+	}
+
+	return h
+}
+
+// transportFor returns a copy of the base transport that always dials addr.
+func (t *BalancingTransport) transportFor(addr string) *http.Transport {
+	tr := t.base.Clone()
+	tr.Proxy = nil // Connections go to addr directly.
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	tr.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return tr
+}
+
+// CloseIdleConnections closes the idle connections of all per-address transports.
+func (t *BalancingTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.base.CloseIdleConnections()
+	for _, h := range t.hosts {
+		for _, tr := range h.transports {
+			tr.CloseIdleConnections()
+		}
+	}
+}
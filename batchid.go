@@ -0,0 +1,57 @@
+package lokilogger
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+)
+
+// batchIDKey is the structured metadata field carrying the fingerprint of the batch with BatchIDs.
+const batchIDKey = "batch_id"
+
+// batchID returns a fingerprint of the labels, timestamps and lines of the streams. It stays the
+// same across retries, so duplicates ingested by a retried push can be recognized downstream.
+func batchID(streams []Stream) string {
+	h := fnv.New64a()
+	var ts [8]byte
+
+	for _, s := range streams {
+		io.WriteString(h, labelsKey(s.Labels))
+		for _, e := range s.Entries {
+			binary.BigEndian.PutUint64(ts[:], uint64(e.Time.UnixNano()))
+			h.Write(ts[:])
+			io.WriteString(h, e.Line)
+			h.Write([]byte{0})
+		}
+	}
+
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// tagBatch attaches the fingerprint of the streams to every entry as the batch_id structured metadata field.
+func tagBatch(streams []Stream) {
+	id := batchID(streams)
+
+	for _, s := range streams {
+		for i, e := range s.Entries {
+			// The metadata map may be shared between entries, e.g. by middlewares.
+			md := make(map[string]string, len(e.Metadata)+1)
+			for k, v := range e.Metadata {
+				md[k] = v
+			}
+			md[batchIDKey] = id
+			s.Entries[i].Metadata = md
+		}
+	}
+}
+
+// streamsBatchID returns the fingerprint attached to the streams by tagBatch, or "" without BatchIDs.
+func streamsBatchID(streams []Stream) string {
+	for _, s := range streams {
+		for _, e := range s.Entries {
+			return e.Metadata[batchIDKey]
+		}
+	}
+	return ""
+}
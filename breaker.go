@@ -0,0 +1,101 @@
+package lokilogger
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const defaultBreakerCooldown = 30 * time.Second
+
+// errBreakerOpen is returned for pushes short-circuited by the open circuit breaker.
+var errBreakerOpen = errors.New("circuit breaker open")
+
+// breaker is a circuit breaker around the primary sink. It opens after
+// threshold consecutive failures and rejects pushes for the cooldown period.
+// Then a single push is let through as a probe: success or a permanent
+// rejection closes the breaker, failure opens it again for another cooldown
+// period.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a push may be sent.
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+// success closes the breaker.
+func (b *breaker) success() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures, b.probing = 0, false
+}
+
+// release ends a probe aborted before it failed or succeeded, e.g. by the shutdown, so that the next push
+// probes again.
+func (b *breaker) release() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// failure records a failed push and reports whether it opened the breaker.
+func (b *breaker) failure() bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.failures < b.threshold {
+		return false
+	}
+
+	b.openUntil, b.probing = time.Now().Add(b.cooldown), false
+	return true
+}
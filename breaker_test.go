@@ -0,0 +1,67 @@
+package lokilogger
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBreakerClosesAfterRejectedProbe(t *testing.T) {
+	withoutStdout(t)
+
+	sink := &testSink{push: func(ctx context.Context, attempt int) error {
+		switch attempt {
+		case 1:
+			return &PushError{StatusCode: http.StatusServiceUnavailable}
+		case 2:
+			return &PushError{StatusCode: http.StatusBadRequest, Body: "invalid labels"}
+		}
+		return nil
+	}}
+	l := newTestLogger(t, context.Background(), Config{Sink: sink, RetryCount: 1, BreakerThreshold: 1, BreakerCooldown: 10 * time.Millisecond})
+	defer l.Close(context.Background())
+
+	batch := func() []Stream {
+		return []Stream{{Labels: map[string]string{"app": "test"}, Entries: []Entry{{Time: time.Now(), Line: "hello"}}}}
+	}
+
+	// The 503 opens the breaker and the held batch is probed after the cooldown, failing with a 400.
+	l.push(sink, true, batch())
+	b := l.breaker.Load()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		b.mu.Lock()
+		closed := b.failures == 0 && !b.probing
+		b.mu.Unlock()
+		if closed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("breaker still open after the rejected probe")
+		}
+	}
+
+	l.push(sink, true, batch())
+	if attempts, entries := sink.counts(); attempts != 3 || entries != 1 {
+		t.Errorf("got %d attempts pushing %d entries, want 3 pushing 1", attempts, entries)
+	}
+}
+
+func TestBreakerReleasesAbortedProbe(t *testing.T) {
+	b := newBreaker(1, time.Millisecond)
+	if !b.failure() {
+		t.Fatal("breaker not opened")
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	if !b.allow() {
+		t.Fatal("probe not allowed after the cooldown")
+	}
+	if b.allow() {
+		t.Fatal("second probe allowed")
+	}
+	b.release()
+	if !b.allow() {
+		t.Error("no probe allowed after the aborted one")
+	}
+}
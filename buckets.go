@@ -0,0 +1,72 @@
+package lokilogger
+
+import (
+	"strconv"
+	"time"
+)
+
+// BatchMode selects how the collected entries are grouped into batches.
+type BatchMode int
+
+const (
+	// BatchGlobal collects all entries in one batch, flushed once it holds BatchSize entries or after
+	// FlushInterval.
+	BatchGlobal BatchMode = iota
+	// BatchPerLevel collects the entries of every level in a buffer of its own.
+	BatchPerLevel
+	// BatchPerStream collects the entries of every stream in a buffer of its own.
+	BatchPerStream
+)
+
+// bucket buffers the entries of a level or stream with BatchPerLevel or BatchPerStream.
+type bucket struct {
+	entries []Entry
+	since   time.Time // Time the oldest entry was buffered.
+}
+
+// bucketKey returns the key of the buffer of the entry.
+func bucketKey(mode BatchMode, e Entry) string {
+	if mode == BatchPerLevel {
+		return e.Level
+	}
+	return strconv.Itoa(e.route) + ":" + strconv.Quote(e.Tenant) + ":" + strconv.Quote(e.Level) + ":" + labelsKey(e.Labels)
+}
+
+// bucket moves the collected logs to the buffers of their level or stream and returns the entries due:
+// the buffers buffered for FlushInterval and the full batches of the others, or all entries if all is set.
+// It must be called with mu held.
+func (l *LokiLogger) bucket(cfg *Config, logs []Entry, all bool) []Entry {
+	if cfg.BatchBy == BatchGlobal && len(l.buckets) == 0 {
+		return logs
+	}
+	if l.buckets == nil {
+		l.buckets = make(map[string]*bucket)
+	}
+
+	now := cfg.Clock.Now()
+	for _, e := range logs {
+		key := bucketKey(cfg.BatchBy, e)
+		b := l.buckets[key]
+		if b == nil {
+			b = &bucket{since: now}
+			l.buckets[key] = b
+		}
+		b.entries = append(b.entries, e)
+	}
+	l.bucketed += len(logs)
+
+	var batch []Entry
+	for key, b := range l.buckets {
+		if all || cfg.BatchBy == BatchGlobal || now.Sub(b.since) >= cfg.FlushInterval {
+			batch = append(batch, b.entries...)
+			l.bucketed -= len(b.entries)
+			delete(l.buckets, key)
+		} else if n := len(b.entries) / cfg.BatchSize * cfg.BatchSize; n > 0 {
+			// Full batches are sent, the rest waits for more entries.
+			batch = append(batch, b.entries[:n]...)
+			l.bucketed -= n
+			b.entries = append(b.entries[:0:0], b.entries[n:]...)
+		}
+	}
+	return batch
+}
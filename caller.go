@@ -0,0 +1,52 @@
+package lokilogger
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// callerKey is the structured metadata field holding the source location of an entry.
+const callerKey = "caller"
+
+// pkgPath is the import path of the package, whose frames are skipped when looking up the caller.
+var pkgPath = reflect.TypeOf(Entry{}).PkgPath()
+
+// cutCaller splits the "file.go:123: " prefix written by the standard logger with
+// log.Lshortfile or log.Llongfile off the line.
+func cutCaller(line string) (caller, rest string, ok bool) {
+	i := strings.Index(line, ": ")
+	if i < 0 {
+		return "", line, false
+	}
+
+	loc := line[:i]
+	colon := strings.LastIndexByte(loc, ':')
+	if colon < 0 || !strings.HasSuffix(loc[:colon], ".go") || strings.ContainsAny(loc, " \t") {
+		return "", line, false
+	}
+	if _, err := strconv.Atoi(loc[colon+1:]); err != nil {
+		return "", line, false
+	}
+
+	return loc, line[i+2:], true
+}
+
+// callerLocation returns the file:line of the first caller outside the package.
+func callerLocation() string {
+	var pcs [16]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPath+".") {
+			return filepath.Base(f.File) + ":" + strconv.Itoa(f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package lokilogger
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrCardinalityLimit is reported to Config.OnError when extra labels are demoted to structured metadata
+// because MaxStreams distinct label combinations were seen already.
+var ErrCardinalityLimit = errors.New("stream cardinality limit reached")
+
+// cardinalityGuard tracks the distinct combinations of extra labels and tenants, bounding the streams
+// created in Loki by labels with dynamic values such as user or request IDs.
+type cardinalityGuard struct {
+	limit int
+
+	mu       sync.Mutex
+	seen     map[string]struct{}
+	reported map[string]struct{} // Label names reported to OnError, so that every offender is reported once.
+}
+
+func newCardinalityGuard(limit int) *cardinalityGuard {
+	if limit <= 0 {
+		return nil
+	}
+	return &cardinalityGuard{limit: limit, seen: make(map[string]struct{}), reported: make(map[string]struct{})}
+}
+
+// check admits the labels of the entry while fewer than limit combinations were seen. Past the limit the
+// extra labels of new combinations are moved to structured metadata, returning their names to report
+// the first time they are demoted.
+func (g *cardinalityGuard) check(e Entry) (Entry, []string) {
+	if g == nil || len(e.Labels) == 0 {
+		return e, nil
+	}
+
+	key := strconv.Quote(e.Tenant) + ":" + labelsKey(e.Labels)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.seen[key]; ok {
+		return e, nil
+	}
+	if len(g.seen) < g.limit {
+		g.seen[key] = struct{}{}
+		return e, nil
+	}
+
+	names := slices.Sorted(maps.Keys(e.Labels))
+	md := make(map[string]string, len(e.Metadata)+len(e.Labels))
+	maps.Copy(md, e.Metadata)
+	maps.Copy(md, e.Labels)
+	e.Metadata = md
+	e.Labels = nil
+
+	if id := strings.Join(names, ","); len(g.reported) < g.limit {
+		if _, ok := g.reported[id]; !ok {
+			g.reported[id] = struct{}{}
+			return e, names
+		}
+	}
+	return e, nil
+}
+
+// onCardinality reports the demoted labels to the OnError hook and the internal logger.
+func (l *LokiLogger) onCardinality(names []string) {
+	err := fmt.Errorf("%w: labels %s demoted to structured metadata", ErrCardinalityLimit, strings.Join(names, ", "))
+	l.logf("warn", "Cardinality guard: %v", err)
+
+	if hook := l.config().OnError; hook != nil {
+		hook(FailedPush{Entries: 1, Err: err})
+	}
+}
@@ -0,0 +1,81 @@
+package lokilogger
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+)
+
+// Child is a logger contributing extra stream labels to the entries it ships
+// through the batching pipeline of its parent LokiLogger, e.g. per subsystem
+// or per tenant. It is created with LokiLogger.With.
+type Child struct {
+	l      *LokiLogger
+	labels map[string]string
+}
+
+// With returns a child logger attaching the labels to the streams of its entries.
+// Label names are sanitized for Loki.
+func (l *LokiLogger) With(labels map[string]string) *Child {
+	return &Child{l: l, labels: mergeLabels(nil, labels)}
+}
+
+// With returns a child logger with the labels added to the labels of c.
+func (c *Child) With(labels map[string]string) *Child {
+	return &Child{l: c.l, labels: mergeLabels(c.labels, labels)}
+}
+
+// Labels returns a copy of the extra stream labels of the child.
+func (c *Child) Labels() map[string]string {
+	return maps.Clone(c.labels)
+}
+
+// Parent returns the logger shipping the entries of the child.
+func (c *Child) Parent() *LokiLogger {
+	return c.l
+}
+
+// Write implements io.Writer like LokiLogger.Write, e.g. for log.New(child, "", log.LstdFlags).
+func (c *Child) Write(p []byte) (n int, err error) {
+	return c.l.write(p, c.labels)
+}
+
+// LogCtx logs msg like LokiLogger.LogCtx with the labels of the child.
+func (c *Child) LogCtx(ctx context.Context, level, msg string, attrs ...slog.Attr) {
+	c.l.logCtx(ctx, c.labels, level, msg, attrs...)
+}
+
+// mergeLabels returns a copy of base with the sanitized labels added.
+func mergeLabels(base, labels map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(labels))
+	maps.Copy(merged, base)
+	for k, v := range labels {
+		merged[sanitizeLabelName(k)] = v
+	}
+	return merged
+}
+
+type childKey struct{}
+
+// WithContext returns a copy of ctx carrying the child, see FromContext.
+func (c *Child) WithContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, childKey{}, c)
+}
+
+// WithContext returns a copy of ctx carrying the logger without extra labels, see FromContext.
+func (l *LokiLogger) WithContext(ctx context.Context) context.Context {
+	return l.With(nil).WithContext(ctx)
+}
+
+// FromContext returns the child logger stored in ctx by WithContext. Without
+// one, it returns a child of the logger created by Init, or nil if Init has
+// not been called.
+func FromContext(ctx context.Context) *Child {
+	if c, ok := ctx.Value(childKey{}).(*Child); ok {
+		return c
+	}
+	if l := Default(); l != nil {
+		return l.With(nil)
+	}
+	return nil
+}
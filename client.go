@@ -0,0 +1,337 @@
+package lokilogger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is a read-side client of the Loki HTTP API.
+type Client struct {
+	URL         string // Loki URL; only scheme and host are used, e.g. http://loki:3100.
+	AccessToken string // Authentication token for accessing the Loki API.
+	TenantID    string // Tenant sent as the X-Scope-OrgID header.
+	HTTPClient  *http.Client
+}
+
+// Client returns a read-side client using the logger's URL, credentials and HTTP client.
+func (l *LokiLogger) Client() *Client {
+	cfg := l.config()
+	return &Client{URL: httpURL(cfg.URL, cfg.Protocol, cfg.PushPath), AccessToken: cfg.AccessToken, TenantID: cfg.TenantID, HTTPClient: l.client}
+}
+
+// QueryResult is the typed result of a LogQL query. Log queries fill Streams,
+// metric queries fill Series.
+type QueryResult struct {
+	Type    string // streams, matrix, vector or scalar.
+	Streams []Stream
+	Series  []MetricSeries
+}
+
+// MetricSeries is a series of samples returned by a metric query.
+type MetricSeries struct {
+	Metric map[string]string
+	Points []Point
+}
+
+// Point is a single sample of a metric series.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// newRequest returns an authenticated request of path on the Loki instance.
+func (c *Client) newRequest(ctx context.Context, method, path string, params url.Values) (*http.Request, error) {
+	u, err := apiURL(c.URL, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	}
+
+	if c.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", c.TenantID)
+	}
+
+	return req, nil
+}
+
+// httpClient returns the configured HTTP client or http.DefaultClient.
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// do performs an authenticated request of path on the Loki instance.
+func (c *Client) do(ctx context.Context, method, path string, params url.Values) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.httpClient().Do(req)
+}
+
+// getJSON performs a GET request of path and decodes the JSON response into v.
+func (c *Client) getJSON(ctx context.Context, path string, params url.Values, v any) error {
+	resp, err := c.do(ctx, "GET", path, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return checkResponse(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return nil
+}
+
+// Ready calls the Loki /ready endpoint and returns an error unless Loki reports ready.
+func (c *Client) Ready(ctx context.Context) error {
+	resp, err := c.do(ctx, "GET", "/ready", nil)
+	if err != nil {
+		return err
+	}
+
+	if err := checkResponse(resp); err != nil {
+		return fmt.Errorf("loki is not ready: %w", err)
+	}
+
+	return nil
+}
+
+// Ping verifies connectivity to Loki and returns its build information.
+func (c *Client) Ping(ctx context.Context) (*BuildInfo, error) {
+	var info BuildInfo
+	if err := c.getJSON(ctx, "/loki/api/v1/status/buildinfo", nil, &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// Query evaluates the LogQL query at a single point in time t, returning at most limit log entries.
+func (c *Client) Query(ctx context.Context, logql string, limit int, t time.Time) (*QueryResult, error) {
+	params := url.Values{"query": {logql}}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	if !t.IsZero() {
+		params.Set("time", strconv.FormatInt(t.UnixNano(), 10))
+	}
+
+	return c.query(ctx, "/loki/api/v1/query", params)
+}
+
+// QueryRange evaluates the LogQL query over the time range [start, end], returning at most limit log entries.
+func (c *Client) QueryRange(ctx context.Context, logql string, limit int, start, end time.Time) (*QueryResult, error) {
+	params := url.Values{"query": {logql}}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	if !start.IsZero() {
+		params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	}
+	if !end.IsZero() {
+		params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	}
+
+	return c.query(ctx, "/loki/api/v1/query_range", params)
+}
+
+// queryResponse is the JSON representation of a query response.
+type queryResponse struct {
+	Data struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+func (c *Client) query(ctx context.Context, path string, params url.Values) (*QueryResult, error) {
+	var resp queryResponse
+	if err := c.getJSON(ctx, path, params, &resp); err != nil {
+		return nil, err
+	}
+
+	return decodeQueryResult(resp.Data.ResultType, resp.Data.Result)
+}
+
+// decodeQueryResult converts the raw result of the given type into a QueryResult.
+func decodeQueryResult(resultType string, raw json.RawMessage) (*QueryResult, error) {
+	res := &QueryResult{Type: resultType}
+
+	switch resultType {
+	case "streams":
+		var streams []struct {
+			Stream map[string]string   `json:"stream"`
+			Values [][]json.RawMessage `json:"values"`
+		}
+		if err := json.Unmarshal(raw, &streams); err != nil {
+			return nil, fmt.Errorf("decode streams: %w", err)
+		}
+
+		for _, s := range streams {
+			out := Stream{Labels: s.Stream, Entries: make([]Entry, 0, len(s.Values))}
+			for _, v := range s.Values {
+				e, err := decodeStreamValue(v)
+				if err != nil {
+					return nil, err
+				}
+				e.Level = s.Stream["level"]
+				out.Entries = append(out.Entries, e)
+			}
+			res.Streams = append(res.Streams, out)
+		}
+	case "matrix":
+		var matrix []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][2]any          `json:"values"`
+		}
+		if err := json.Unmarshal(raw, &matrix); err != nil {
+			return nil, fmt.Errorf("decode matrix: %w", err)
+		}
+
+		for _, m := range matrix {
+			series := MetricSeries{Metric: m.Metric}
+			for _, v := range m.Values {
+				series.Points = append(series.Points, decodePoint(v))
+			}
+			res.Series = append(res.Series, series)
+		}
+	case "vector":
+		var vector []struct {
+			Metric map[string]string `json:"metric"`
+			Value  [2]any            `json:"value"`
+		}
+		if err := json.Unmarshal(raw, &vector); err != nil {
+			return nil, fmt.Errorf("decode vector: %w", err)
+		}
+
+		for _, v := range vector {
+			res.Series = append(res.Series, MetricSeries{Metric: v.Metric, Points: []Point{decodePoint(v.Value)}})
+		}
+	case "scalar":
+		var scalar [2]any
+		if err := json.Unmarshal(raw, &scalar); err != nil {
+			return nil, fmt.Errorf("decode scalar: %w", err)
+		}
+
+		res.Series = []MetricSeries{{Points: []Point{decodePoint(scalar)}}}
+	default:
+		return nil, fmt.Errorf("unsupported result type %q", resultType)
+	}
+
+	return res, nil
+}
+
+// decodeStreamValue decodes a [timestamp, line, metadata] log value.
+func decodeStreamValue(v []json.RawMessage) (Entry, error) {
+	var e Entry
+	if len(v) < 2 {
+		return e, fmt.Errorf("invalid stream value of length %d", len(v))
+	}
+
+	var ts string
+	if err := json.Unmarshal(v[0], &ts); err != nil {
+		return e, fmt.Errorf("decode timestamp: %w", err)
+	}
+	ns, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return e, fmt.Errorf("decode timestamp: %w", err)
+	}
+	e.Time = time.Unix(0, ns)
+
+	if err := json.Unmarshal(v[1], &e.Line); err != nil {
+		return e, fmt.Errorf("decode line: %w", err)
+	}
+
+	if len(v) > 2 {
+		// Structured metadata, optionally wrapped by categorized labels responses.
+		var md map[string]json.RawMessage
+		if json.Unmarshal(v[2], &md) == nil {
+			e.Metadata = make(map[string]string, len(md))
+			for k, raw := range md {
+				var s string
+				if json.Unmarshal(raw, &s) == nil {
+					e.Metadata[k] = s
+					continue
+				}
+				var nested map[string]string
+				if json.Unmarshal(raw, &nested) == nil {
+					for nk, nv := range nested {
+						e.Metadata[nk] = nv
+					}
+				}
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// decodePoint decodes a [unix seconds, "value"] sample.
+func decodePoint(v [2]any) Point {
+	var p Point
+	if sec, ok := v[0].(float64); ok {
+		p.Time = time.Unix(0, int64(sec*float64(time.Second)))
+	}
+	if s, ok := v[1].(string); ok {
+		p.Value, _ = strconv.ParseFloat(strings.TrimSpace(s), 64)
+	}
+	return p
+}
+
+// dataResponse is the JSON representation of the label and series API responses.
+type dataResponse[T any] struct {
+	Data T `json:"data"`
+}
+
+// Labels returns the label names known to Loki.
+func (c *Client) Labels(ctx context.Context) ([]string, error) {
+	var resp dataResponse[[]string]
+	if err := c.getJSON(ctx, "/loki/api/v1/labels", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// LabelValues returns the values known to Loki for the label name.
+func (c *Client) LabelValues(ctx context.Context, name string) ([]string, error) {
+	var resp dataResponse[[]string]
+	if err := c.getJSON(ctx, "/loki/api/v1/label/"+url.PathEscape(name)+"/values", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// Series returns the label sets of the streams matching any of the stream selectors, e.g. {service_name="api"}.
+func (c *Client) Series(ctx context.Context, matchers ...string) ([]map[string]string, error) {
+	var resp dataResponse[[]map[string]string]
+	if err := c.getJSON(ctx, "/loki/api/v1/series", url.Values{"match[]": matchers}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
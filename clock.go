@@ -0,0 +1,45 @@
+package lokilogger
+
+import (
+	"context"
+	"time"
+)
+
+// Clock provides the time for the flush timer and the waits between retries.
+// Tests can set Config.Clock to a fake clock, e.g. lokiloggertest.FakeClock,
+// to drive batching and flushing without sleeping.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer created by a Clock, mirroring time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// realClock is the Clock of the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// sleep waits for d on the configured clock. It reports false if the context is done first.
+func (l *LokiLogger) sleep(ctx context.Context, d time.Duration) bool {
+	t := l.config().Clock.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C():
+		return true
+	}
+}
@@ -0,0 +1,295 @@
+// Command lokiship reads lines from stdin or files and pushes them to Loki, e.g. for cron jobs and shell
+// scripts. Lines are parsed like lines written to the logger: a leading timestamp sets the time and a level
+// token the level, unless -level is given. Every line is echoed to stdout, so lokiship can sit in a pipe:
+//
+//	backup.sh 2>&1 | lokiship -url http://loki:3100 -name backup -label job=nightly
+//	lokiship -config loki.yaml -level error /var/log/app/errors.log
+//
+// The configuration is read from the LOKI_* environment variables, see lokilogger.ConfigFromEnv, or the
+// -config file; flags override both. lokiship exits with status 1 if any line could not be delivered.
+//
+// The query and tail subcommands read the logs back with the same configuration and credentials, printing
+// the entries oldest first with their time and stream labels:
+//
+//	lokiship query -since 1h -limit 50 '{service_name="api"} |= "timeout"'
+//	lokiship tail '{service_name="api", level="error"}'
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	lokilogger "github.com/LynxXIII/loki_logger"
+)
+
+// labelFlags collects repeated -label key=value flags.
+type labelFlags map[string]string
+
+func (f labelFlags) String() string {
+	pairs := make([]string, 0, len(f))
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f labelFlags) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("invalid label %q: must be key=value", s)
+	}
+	f[k] = v
+	return nil
+}
+
+// options are the flags selecting the configuration, shared by all subcommands.
+type options struct {
+	configPath string
+	url        string
+	name       string
+	tenant     string
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.configPath, "config", "", "YAML or JSON configuration file")
+	fs.StringVar(&o.url, "url", "", "Loki URL (default $LOKI_URL)")
+	fs.StringVar(&o.name, "name", "", "service name (default $LOKI_NAME)")
+	fs.StringVar(&o.tenant, "tenant", "", "tenant sent as X-Scope-OrgID (default $LOKI_TENANT)")
+}
+
+// logger returns a logger of the configuration with the flags applied.
+func (o *options) logger() (*lokilogger.LokiLogger, error) {
+	cfg, err := config(o.configPath)
+	if err != nil {
+		return nil, err
+	}
+	if o.url != "" {
+		cfg.URL = o.url
+	}
+	if o.name != "" {
+		cfg.Name = o.name
+	}
+	if o.tenant != "" {
+		cfg.TenantID = o.tenant
+	}
+
+	return lokilogger.New(cfg.URL, lokilogger.WithConfig(func(c *lokilogger.Config) { *c = cfg }))
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("lokiship: ")
+
+	args := os.Args[1:]
+	cmd := "push"
+	if len(args) > 0 && (args[0] == "push" || args[0] == "query" || args[0] == "tail") {
+		cmd, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	var err error
+	switch cmd {
+	case "push":
+		err = push(fs, args)
+	case "query":
+		err = query(fs, args)
+	case "tail":
+		err = tail(fs, args)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// push ships the lines of the files or stdin.
+func push(fs *flag.FlagSet, args []string) error {
+	var opts options
+	labels := labelFlags{}
+	opts.register(fs)
+	level := fs.String("level", "", "level of all lines instead of detecting it")
+	timeout := fs.Duration("timeout", 10*time.Second, "maximum time to deliver the remaining lines at the end")
+	fs.Var(labels, "label", "stream label as key=value, repeatable")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: lokiship [push] [flags] [file ...]\n       lokiship query|tail [flags] <logql>\n\nReads stdin when no file is given.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	l, err := opts.logger()
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = l.With(labels)
+	if *level != "" {
+		w = l.StdLogger(*level, labels).Writer()
+	}
+
+	readErr := ship(w, fs.Args())
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err := l.Shutdown(ctx); err != nil {
+		return err
+	}
+	if readErr != nil {
+		return readErr
+	}
+	if failed := l.Stats().Failed; failed > 0 {
+		return fmt.Errorf("%d lines could not be delivered", failed)
+	}
+	return nil
+}
+
+// query prints the result of a LogQL query over the last -since.
+func query(fs *flag.FlagSet, args []string) error {
+	var opts options
+	opts.register(fs)
+	since := fs.Duration("since", time.Hour, "start of the queried range before now")
+	limit := fs.Int("limit", 100, "maximum number of entries")
+	timeout := fs.Duration("timeout", 30*time.Second, "maximum time of the query")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: lokiship query [flags] <logql>\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	l, err := opts.logger()
+	if err != nil {
+		return err
+	}
+	defer l.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	end := time.Now()
+	res, err := l.Client().QueryRange(ctx, fs.Arg(0), *limit, end.Add(-*since), end)
+	if err != nil {
+		return err
+	}
+
+	var entries []lokilogger.Entry
+	for _, s := range res.Streams {
+		for _, e := range s.Entries {
+			e.Labels = s.Labels
+			entries = append(entries, e)
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b lokilogger.Entry) int { return a.Time.Compare(b.Time) })
+	for _, e := range entries {
+		printEntry(e)
+	}
+
+	for _, s := range res.Series {
+		for _, p := range s.Points {
+			fmt.Printf("%s %s %g\n", p.Time.Format(time.RFC3339Nano), formatLabels(s.Metric), p.Value)
+		}
+	}
+	return nil
+}
+
+// tail prints the entries matching a LogQL query as they arrive until interrupted.
+func tail(fs *flag.FlagSet, args []string) error {
+	var opts options
+	opts.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: lokiship tail [flags] <logql>\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	l, err := opts.logger()
+	if err != nil {
+		return err
+	}
+	defer l.Shutdown(context.Background())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	entries, err := l.Client().Tail(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	for e := range entries {
+		printEntry(e)
+	}
+	if ctx.Err() == nil {
+		return fmt.Errorf("tail: connection lost")
+	}
+	return nil
+}
+
+// printEntry prints the entry with its time and stream labels.
+func printEntry(e lokilogger.Entry) {
+	fmt.Printf("%s %s %s\n", e.Time.Format(time.RFC3339Nano), formatLabels(e.Labels), e.Line)
+}
+
+// formatLabels returns the labels in LogQL selector syntax, e.g. {level="info", service_name="api"}.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, v))
+	}
+	slices.Sort(pairs)
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// config returns the configuration of the file, or of the environment without one.
+func config(path string) (lokilogger.Config, error) {
+	if path != "" {
+		return lokilogger.LoadConfig(path)
+	}
+	return lokilogger.ConfigFromEnv()
+}
+
+// ship writes every line of the files, or of stdin without files, to w.
+func ship(w io.Writer, paths []string) error {
+	if len(paths) == 0 {
+		return shipLines(w, os.Stdin)
+	}
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		err = shipLines(w, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// shipLines writes every non-empty line of r to w.
+func shipLines(w io.Writer, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); len(line) > 0 {
+			if _, err := w.Write(line); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,62 @@
+package lokilogger
+
+import "time"
+
+// coalescer holds the batches deferred by CoalesceWindow.
+type coalescer struct {
+	entries []Entry   // Deferred entries, in order.
+	last    time.Time // Time of the last push.
+	timer   Timer
+	stop    chan struct{} // Closed when the timer is stopped.
+}
+
+// coalesce returns the entries to push now, the batch after the deferred entries, or nil if the batch is
+// deferred until the window after the last push ends. It must be called with mu held.
+func (l *LokiLogger) coalesce(cfg *Config, batch []Entry, force bool) []Entry {
+	c := &l.coalescer
+	if cfg.CoalesceWindow <= 0 && len(c.entries) == 0 {
+		return batch
+	}
+
+	c.entries = append(c.entries, batch...)
+	if len(c.entries) == 0 {
+		return nil
+	}
+
+	now := cfg.Clock.Now()
+	wait := cfg.CoalesceWindow - now.Sub(c.last)
+	if force || wait <= 0 || len(c.entries) >= cfg.BatchSize {
+		if c.timer != nil {
+			c.timer.Stop()
+			close(c.stop)
+			c.timer, c.stop = nil, nil
+		}
+		batch, c.entries = c.entries, nil
+		c.last = now
+		return batch
+	}
+
+	if c.timer == nil {
+		t, stop := cfg.Clock.NewTimer(wait), make(chan struct{})
+		c.timer, c.stop = t, stop
+		go func() {
+			select {
+			case <-t.C():
+			case <-stop:
+				return
+			case <-l.stopped:
+				return
+			}
+
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			// The deferred entries were pushed while waiting for the lock.
+			if l.coalescer.timer != t {
+				return
+			}
+			l.coalescer.timer, l.coalescer.stop = nil, nil
+			l.prepareLogs(true)
+		}()
+	}
+	return nil
+}
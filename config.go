@@ -0,0 +1,330 @@
+package lokilogger
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 5 * time.Second
+	defaultRetryCount    = 3
+)
+
+// setDefaults replaces zero values with their defaults.
+func (c *Config) setDefaults() {
+	if c.AdaptiveBatching != nil {
+		a := *c.AdaptiveBatching
+		a.setDefaults()
+		c.AdaptiveBatching = &a
+		c.BatchSize, c.FlushInterval = a.MinBatchSize, a.MinFlushInterval
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.FlushInterval == 0 {
+		c.FlushInterval = defaultFlushInterval
+	}
+	if c.RetryCount == 0 {
+		c.RetryCount = defaultRetryCount
+	}
+	if c.MaxQueueSize == 0 {
+		c.MaxQueueSize = max(defaultMaxQueueSize, c.BatchSize)
+		if c.AdaptiveBatching != nil {
+			c.MaxQueueSize = max(c.MaxQueueSize, c.AdaptiveBatching.MaxBatchSize)
+		}
+	}
+	if c.MaxLabels == 0 {
+		c.MaxLabels = defaultMaxLabels
+	}
+	if c.MaxLabelValueLength == 0 {
+		c.MaxLabelValueLength = defaultMaxLabelValueLength
+	}
+	if c.Protocol == "" {
+		c.Protocol = detectProtocol(c.URL)
+	}
+	if c.InternalLogger == nil {
+		c.InternalLogger = os.Stderr
+	}
+	if c.DryRunOutput == nil {
+		c.DryRunOutput = os.Stderr
+	}
+	if c.Clock == nil {
+		c.Clock = realClock{}
+	}
+}
+
+// validate returns a descriptive error for invalid values and combinations.
+func (c *Config) validate() error {
+	if c.BatchSize < 0 {
+		return fmt.Errorf("invalid BatchSize %d: must be positive", c.BatchSize)
+	}
+	if c.FlushInterval < 0 {
+		return fmt.Errorf("invalid FlushInterval %s: must be positive", c.FlushInterval)
+	}
+	if c.RequestTimeout < 0 || c.IdleConnTimeout < 0 {
+		return fmt.Errorf("invalid RequestTimeout %s or IdleConnTimeout %s: must not be negative", c.RequestTimeout, c.IdleConnTimeout)
+	}
+	if c.PushTimeout < 0 {
+		return fmt.Errorf("invalid PushTimeout %s: must not be negative", c.PushTimeout)
+	}
+	if c.MaxIdleConns < 0 || c.MaxConnsPerHost < 0 {
+		return fmt.Errorf("invalid MaxIdleConns %d or MaxConnsPerHost %d: must not be negative", c.MaxIdleConns, c.MaxConnsPerHost)
+	}
+	if c.ProxyURL != "" {
+		if err := validateProxyURL(c.ProxyURL); err != nil {
+			return err
+		}
+		if c.LoadBalance {
+			return fmt.Errorf("ProxyURL can't be combined with LoadBalance")
+		}
+	}
+	if c.SigV4 != nil {
+		if c.SigV4.region() == "" {
+			return fmt.Errorf("SigV4 requires a Region or the AWS_REGION environment variable")
+		}
+		if c.AccessToken != "" {
+			return fmt.Errorf("SigV4 can't be combined with AccessToken")
+		}
+	}
+	if c.OAuth2 != nil {
+		if c.OAuth2.TokenURL == "" || c.OAuth2.ClientID == "" {
+			return fmt.Errorf("OAuth2 requires a TokenURL and ClientID")
+		}
+		if _, err := url.Parse(c.OAuth2.TokenURL); err != nil {
+			return fmt.Errorf("invalid OAuth2 TokenURL: %w", err)
+		}
+		if c.AccessToken != "" || c.SigV4 != nil {
+			return fmt.Errorf("OAuth2 can't be combined with AccessToken or SigV4")
+		}
+	}
+	if c.AccessTokenFile != "" {
+		if _, err := readTokenFile(c.AccessTokenFile); err != nil {
+			return fmt.Errorf("invalid AccessTokenFile: %w", err)
+		}
+		if c.AccessToken != "" || c.SigV4 != nil || c.OAuth2 != nil {
+			return fmt.Errorf("AccessTokenFile can't be combined with AccessToken, SigV4 or OAuth2")
+		}
+	}
+	if c.AccessTokenRefresh < 0 {
+		return fmt.Errorf("invalid AccessTokenRefresh %s: must not be negative", c.AccessTokenRefresh)
+	}
+	if c.MaxStreams < 0 || c.MaxStreamsPerPush < 0 {
+		return fmt.Errorf("invalid MaxStreams %d or MaxStreamsPerPush %d: must not be negative", c.MaxStreams, c.MaxStreamsPerPush)
+	}
+	if err := c.validateLabels(); err != nil {
+		return err
+	}
+	for i := range c.Routes {
+		if err := c.Routes[i].validate(); err != nil {
+			return fmt.Errorf("invalid Routes[%d]: %w", i, err)
+		}
+	}
+	pipeline, err := compilePipeline(c.Pipeline)
+	if err != nil {
+		return err
+	}
+	c.pipeline = pipeline
+	if c.DialContext != nil && c.LoadBalance {
+		return fmt.Errorf("DialContext can't be combined with LoadBalance")
+	}
+	if c.CoalesceWindow < 0 {
+		return fmt.Errorf("invalid CoalesceWindow %s: must not be negative", c.CoalesceWindow)
+	}
+	if c.MaxQueueSize < c.BatchSize {
+		return fmt.Errorf("invalid MaxQueueSize %d: must be at least BatchSize %d", c.MaxQueueSize, c.BatchSize)
+	}
+	if a := c.AdaptiveBatching; a != nil {
+		if err := a.validate(); err != nil {
+			return err
+		}
+		if c.MaxQueueSize < a.MaxBatchSize {
+			return fmt.Errorf("invalid MaxQueueSize %d: must be at least AdaptiveBatching.MaxBatchSize %d", c.MaxQueueSize, a.MaxBatchSize)
+		}
+	}
+	if c.HeartbeatInterval < 0 {
+		return fmt.Errorf("invalid HeartbeatInterval %s: must not be negative", c.HeartbeatInterval)
+	}
+	if c.MaxEntryAge < 0 {
+		return fmt.Errorf("invalid MaxEntryAge %s: must not be negative", c.MaxEntryAge)
+	}
+	if c.RetryCount < 0 {
+		return fmt.Errorf("invalid RetryCount %d: must be positive", c.RetryCount)
+	}
+	if c.RetryBudget < 0 {
+		return fmt.Errorf("invalid RetryBudget %g: must not be negative", c.RetryBudget)
+	}
+
+	for level, n := range c.SampleRates {
+		if n < 0 {
+			return fmt.Errorf("invalid SampleRates[%q] %d: must not be negative", level, n)
+		}
+	}
+	if c.DedupWindow < 0 {
+		return fmt.Errorf("invalid DedupWindow %s: must not be negative", c.DedupWindow)
+	}
+	if c.RateLimit < 0 || c.ByteRateLimit < 0 {
+		return fmt.Errorf("invalid RateLimit %g or ByteRateLimit %g: must not be negative", c.RateLimit, c.ByteRateLimit)
+	}
+	if c.Overflow != OverflowDrop && c.Overflow != OverflowBlock {
+		return fmt.Errorf("invalid Overflow %d", c.Overflow)
+	}
+	if _, ok := levels[c.MinLevel]; c.MinLevel != "" && !ok {
+		return fmt.Errorf("invalid MinLevel %q: must be "+levelNames, c.MinLevel)
+	}
+	if _, ok := levels[c.DefaultLevel]; c.DefaultLevel != "" && !ok {
+		return fmt.Errorf("invalid DefaultLevel %q: must be "+levelNames, c.DefaultLevel)
+	}
+	for token, level := range c.LevelTokens {
+		if _, ok := levels[level]; !ok || token == "" {
+			return fmt.Errorf("invalid LevelTokens %q: %q must be "+levelNames, token, level)
+		}
+	}
+	for level, label := range c.LevelLabels {
+		if label == "" {
+			return fmt.Errorf("invalid LevelLabels %q: the label value must not be empty", level)
+		}
+	}
+	for i, p := range c.LevelPatterns {
+		if _, ok := levels[p.Level]; !ok || p.Regexp == nil {
+			return fmt.Errorf("invalid LevelPatterns[%d]: needs a Regexp and a level of "+levelNames, i)
+		}
+	}
+	if _, ok := levels[c.FlushOnLevel]; c.FlushOnLevel != "" && !ok {
+		return fmt.Errorf("invalid FlushOnLevel %q: must be "+levelNames, c.FlushOnLevel)
+	}
+	if c.MaxBufferSize < 0 {
+		return fmt.Errorf("invalid MaxBufferSize %d: must not be negative", c.MaxBufferSize)
+	}
+	if c.BreakerThreshold < 0 || c.BreakerCooldown < 0 {
+		return fmt.Errorf("invalid BreakerThreshold %d or BreakerCooldown %s: must not be negative", c.BreakerThreshold, c.BreakerCooldown)
+	}
+	if c.MultilineTimeout < 0 {
+		return fmt.Errorf("invalid MultilineTimeout %s: must not be negative", c.MultilineTimeout)
+	}
+	if c.MaxLineSize < 0 {
+		return fmt.Errorf("invalid MaxLineSize %d: must not be negative", c.MaxLineSize)
+	}
+	if c.LineFormat != LineFormatText && c.LineFormat != LineFormatLogfmt {
+		return fmt.Errorf("invalid LineFormat %q: must be empty or logfmt", c.LineFormat)
+	}
+	if c.TimestampPolicy != TimestampEvent && c.TimestampPolicy != TimestampArrival {
+		return fmt.Errorf("invalid TimestampPolicy %d", c.TimestampPolicy)
+	}
+	if c.MaxTimestampAge < 0 {
+		return fmt.Errorf("invalid MaxTimestampAge %s: must not be negative", c.MaxTimestampAge)
+	}
+	if c.LineSizePolicy != LineTruncate && c.LineSizePolicy != LineDrop {
+		return fmt.Errorf("invalid LineSizePolicy %d", c.LineSizePolicy)
+	}
+	if c.BatchBy < BatchGlobal || c.BatchBy > BatchPerStream {
+		return fmt.Errorf("invalid BatchBy %d", c.BatchBy)
+	}
+	if c.CancelPolicy != CancelFlush && c.CancelPolicy != CancelDiscard {
+		return fmt.Errorf("invalid CancelPolicy %d", c.CancelPolicy)
+	}
+	if c.Schema != nil {
+		if err := c.Schema.validate(); err != nil {
+			return err
+		}
+	}
+	if c.SpillMaxBytes < 0 || c.SpillMaxAge < 0 {
+		return fmt.Errorf("invalid SpillMaxBytes %d or SpillMaxAge %s: must not be negative", c.SpillMaxBytes, c.SpillMaxAge)
+	}
+
+	if c.Protocol != ProtocolLoki && c.Protocol != ProtocolOTLP && c.Protocol != ProtocolVictoriaLogs {
+		return fmt.Errorf("invalid Protocol %q: must be %q, %q or %q", c.Protocol, ProtocolLoki, ProtocolOTLP, ProtocolVictoriaLogs)
+	}
+	if c.PushPath != "" && !strings.HasPrefix(c.PushPath, "/") {
+		return fmt.Errorf("invalid PushPath %q: must start with /", c.PushPath)
+	}
+
+	if c.StrictOrdering && (c.ReadinessProbe || c.BreakerThreshold > 0) {
+		return fmt.Errorf("StrictOrdering can't be combined with ReadinessProbe or BreakerThreshold")
+	}
+
+	if c.DryRun {
+		if c.ReadinessProbe {
+			return fmt.Errorf("ReadinessProbe can't be combined with DryRun")
+		}
+		if c.URL == "" {
+			return nil
+		}
+		return validatePushURL(c)
+	}
+
+	if c.Sink != nil {
+		if len(c.FailoverURLs) > 0 {
+			return fmt.Errorf("FailoverURLs can't be combined with a custom Sink")
+		}
+		if c.ReadinessProbe && c.URL == "" {
+			return fmt.Errorf("ReadinessProbe requires the Loki URL")
+		}
+		return nil
+	}
+
+	if c.URL == "" {
+		return fmt.Errorf("URL is required unless a Sink is set")
+	}
+
+	if err := validatePushURL(c); err != nil {
+		return err
+	}
+	for _, u := range c.FailoverURLs {
+		if err := validateURL(u); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateProxyURL checks that rawURL is an http, https or socks5 proxy URL. The password is never included in errors.
+func validateProxyURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid ProxyURL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+		return fmt.Errorf("invalid ProxyURL %q: scheme must be http, https or socks5", u.Redacted())
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid ProxyURL %q: missing host", u.Redacted())
+	}
+	return nil
+}
+
+// validatePushURL checks the URL of the configuration: an absolute http or https URL, or unix:///path
+// to push over a unix socket, which can't be combined with other endpoints, a proxy or load balancing.
+func validatePushURL(c *Config) error {
+	path, ok := unixSocketPath(c.URL)
+	if !ok {
+		return validateURL(c.URL)
+	}
+
+	if path == "" {
+		return fmt.Errorf("invalid URL %q: missing socket path", c.URL)
+	}
+	if len(c.FailoverURLs) > 0 || c.LoadBalance || c.ProxyURL != "" {
+		return fmt.Errorf("unix socket URL %q can't be combined with FailoverURLs, LoadBalance or ProxyURL", c.URL)
+	}
+	return nil
+}
+
+// validateURL checks that rawURL is an absolute http or https URL.
+func validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid URL %q: scheme must be http or https", rawURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid URL %q: missing host", rawURL)
+	}
+	return nil
+}
@@ -0,0 +1,540 @@
+package lokilogger
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration read from configuration files as a string
+// such as "5s", or as a number of seconds.
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", v)
+		}
+		*d = Duration(parsed)
+	case float64:
+		*d = Duration(v * float64(time.Second))
+	default:
+		return fmt.Errorf("invalid duration %s", b)
+	}
+
+	return nil
+}
+
+// FileConfig is the representation of a configuration file loaded by LoadConfig.
+type FileConfig struct {
+	URL                string            `json:"url"`
+	Name               string            `json:"name"`
+	TenantID           string            `json:"tenant_id"`
+	AccessToken        string            `json:"access_token"`
+	AccessTokenFile    string            `json:"access_token_file"`
+	AccessTokenRefresh Duration          `json:"access_token_refresh"`
+	Protocol           Protocol          `json:"protocol"`
+	PushPath           string            `json:"push_path"`
+	Labels             map[string]string `json:"labels"`
+	HostLabels         bool              `json:"host_labels"`
+	EnvLabels          []string          `json:"env_labels"`
+	MaxLabels          int               `json:"max_labels"`
+	MaxStreams         int               `json:"max_streams"`
+	MaxStreamsPerPush  int               `json:"max_streams_per_push"`
+	MaxLabelValueLen   int               `json:"max_label_value_length"`
+	SampleRates        map[string]int    `json:"sample_rates"`
+	DedupWindow        Duration          `json:"dedup_window"`
+	MinLevel           string            `json:"min_level"`
+	FlushOnLevel       string            `json:"flush_on_level"`
+	OrderTimestamps    bool              `json:"order_timestamps"`
+	MaxLineSize        int               `json:"max_line_size"`
+	LineSizePolicy     string            `json:"line_size_policy"` // truncate or drop.
+	StrictOrdering     bool              `json:"strict_ordering"`
+	CancelPolicy       string            `json:"cancel_policy"` // flush or discard.
+	BatchIDs           bool              `json:"batch_ids"`
+	DryRun             bool              `json:"dry_run"`
+	Debug              bool              `json:"debug"`
+
+	Batch struct {
+		Size           int      `json:"size"`
+		FlushInterval  Duration `json:"flush_interval"`
+		MaxEntryAge    Duration `json:"max_entry_age"`
+		MaxQueueSize   int      `json:"max_queue_size"`
+		BlockOnFull    bool     `json:"block_on_full"`
+		CoalesceWindow Duration `json:"coalesce_window"`
+		By             string   `json:"by"` // global, level or stream.
+		Adaptive       *struct {
+			MinSize          int      `json:"min_size"`
+			MaxSize          int      `json:"max_size"`
+			MinFlushInterval Duration `json:"min_flush_interval"`
+			MaxFlushInterval Duration `json:"max_flush_interval"`
+		} `json:"adaptive"`
+	} `json:"batch"`
+
+	Retry struct {
+		Count  int     `json:"count"`
+		Budget float64 `json:"budget"`
+		Policy string  `json:"policy"` // fifo or newest_first.
+	} `json:"retry"`
+
+	RateLimit struct {
+		Entries  float64 `json:"entries"`
+		Bytes    float64 `json:"bytes"`
+		Overflow string  `json:"overflow"` // drop or block.
+	} `json:"rate_limit"`
+
+	Failover struct {
+		URLs             []string `json:"urls"`
+		Threshold        int      `json:"threshold"`
+		FailbackInterval Duration `json:"failback_interval"`
+	} `json:"failover"`
+
+	LoadBalance struct {
+		Enabled         bool     `json:"enabled"`
+		ResolveInterval Duration `json:"resolve_interval"`
+	} `json:"load_balance"`
+
+	HTTP struct {
+		Timeout         Duration `json:"timeout"`
+		PushTimeout     Duration `json:"push_timeout"`
+		MaxIdleConns    int      `json:"max_idle_conns"`
+		MaxConnsPerHost int      `json:"max_conns_per_host"`
+		IdleConnTimeout Duration `json:"idle_conn_timeout"`
+		ExpectContinue  bool     `json:"expect_continue"`
+		ProxyURL        string   `json:"proxy_url"`
+	} `json:"http"`
+
+	SigV4 *struct {
+		Region  string `json:"region"`
+		Service string `json:"service"`
+		Profile string `json:"profile"`
+	} `json:"sigv4"`
+
+	LineFormat      LineFormat `json:"line_format"` // empty or logfmt.
+	TimeLayouts     []string   `json:"time_layouts"`
+	TimeZone        string     `json:"time_zone"`        // IANA name, e.g. Europe/Berlin, or Local.
+	TimestampPolicy string     `json:"timestamp_policy"` // event or arrival.
+	MaxTimestampAge Duration   `json:"max_timestamp_age"`
+
+	Schema *struct {
+		Required []string          `json:"required"`
+		Labels   []string          `json:"labels"`
+		Types    map[string]string `json:"types"`  // string, int, float, bool, duration or time.
+		Policy   string            `json:"policy"` // flag, fix or reject.
+	} `json:"schema"`
+
+	Attrs struct {
+		Separator    string   `json:"separator"`
+		Labels       []string `json:"labels"`
+		Drop         []string `json:"drop"`
+		ErrorDetails bool     `json:"error_details"`
+	} `json:"attrs"`
+
+	LevelDetection struct {
+		Default  string            `json:"default"`
+		Labels   map[string]string `json:"labels"`
+		Tokens   map[string]string `json:"tokens"`
+		Patterns []struct {
+			Pattern string `json:"pattern"`
+			Level   string `json:"level"`
+		} `json:"patterns"`
+	} `json:"level_detection"`
+
+	Routes []struct {
+		Level   string            `json:"level"`
+		Labels  map[string]string `json:"labels"`
+		Message string            `json:"message"`
+		Tenant  string            `json:"tenant"`
+		URL     string            `json:"url"`
+	} `json:"routes"`
+
+	PipelineStages []Stage `json:"pipeline_stages"`
+
+	OAuth2 *struct {
+		TokenURL     string            `json:"token_url"`
+		ClientID     string            `json:"client_id"`
+		ClientSecret string            `json:"client_secret"`
+		Scopes       []string          `json:"scopes"`
+		Params       map[string]string `json:"params"`
+	} `json:"oauth2"`
+
+	Readiness struct {
+		Probe         bool     `json:"probe"`
+		Interval      Duration `json:"interval"`
+		MaxBufferSize int      `json:"max_buffer_size"`
+	} `json:"readiness"`
+
+	Spill struct {
+		Dir      string   `json:"dir"`
+		MaxBytes int64    `json:"max_bytes"`
+		MaxAge   Duration `json:"max_age"`
+	} `json:"spill"`
+
+	Multiline struct {
+		Pattern string   `json:"pattern"`
+		Timeout Duration `json:"timeout"`
+	} `json:"multiline"`
+
+	Breaker struct {
+		Threshold int      `json:"threshold"`
+		Cooldown  Duration `json:"cooldown"`
+	} `json:"breaker"`
+
+	Heartbeat struct {
+		Interval Duration `json:"interval"`
+		Version  string   `json:"version"`
+	} `json:"heartbeat"`
+
+	DeadLetterFile string `json:"dead_letter_file"`
+
+	TLS *FileTLSConfig `json:"tls"`
+}
+
+// FileTLSConfig is the TLS section of a configuration file.
+type FileTLSConfig struct {
+	CAFile             string `json:"ca_file"`
+	CertFile           string `json:"cert_file"`
+	KeyFile            string `json:"key_file"`
+	ServerName         string `json:"server_name"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+}
+
+// LoadConfig reads a YAML (.yaml, .yml) or JSON (.json) configuration file,
+// applies the defaults and validates the result. Unknown fields are reported
+// as errors. See FileConfig for the available fields, e.g.
+//
+//	url: http://loki:3100/loki/api/v1/push
+//	name: api
+//	labels:
+//	  env: prod
+//	batch:
+//	  size: 100
+//	  flush_interval: 5s
+//	retry:
+//	  count: 3
+//	tls:
+//	  ca_file: /etc/loki/ca.pem
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		v, err := parseYAML(data)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: %w", path, err)
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", path, err)
+		}
+	case ".json":
+	default:
+		return Config{}, fmt.Errorf("%s: unsupported config format, expected .yaml, .yml or .json", path)
+	}
+
+	var fc FileConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fc); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, describeDecodeError(err))
+	}
+
+	cfg, err := fc.Config()
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	cfg.setDefaults()
+	if err := cfg.validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// describeDecodeError rewrites JSON decoding errors into configuration terms.
+func describeDecodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("field %q: expected %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	}
+	if msg, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return fmt.Errorf("unknown field %s", msg)
+	}
+	return err
+}
+
+// Config converts the file configuration into a Config.
+func (fc *FileConfig) Config() (Config, error) {
+	cfg := Config{
+		URL:                fc.URL,
+		Name:               fc.Name,
+		TenantID:           fc.TenantID,
+		AccessToken:        fc.AccessToken,
+		AccessTokenFile:    fc.AccessTokenFile,
+		AccessTokenRefresh: time.Duration(fc.AccessTokenRefresh),
+		Protocol:           fc.Protocol,
+		PushPath:           fc.PushPath,
+		Labels:             fc.Labels,
+		HostLabels:         fc.HostLabels,
+		EnvLabels:          fc.EnvLabels,
+		MaxLabels:          fc.MaxLabels,
+		MaxStreams:         fc.MaxStreams,
+		MaxStreamsPerPush:  fc.MaxStreamsPerPush,
+		SampleRates:        fc.SampleRates,
+		DedupWindow:        time.Duration(fc.DedupWindow),
+		MinLevel:           fc.MinLevel,
+		FlushOnLevel:       fc.FlushOnLevel,
+		OrderTimestamps:    fc.OrderTimestamps,
+		MaxLineSize:        fc.MaxLineSize,
+		StrictOrdering:     fc.StrictOrdering,
+		BatchIDs:           fc.BatchIDs,
+		DryRun:             fc.DryRun,
+		Debug:              fc.Debug,
+		MultilineTimeout:   time.Duration(fc.Multiline.Timeout),
+		BatchSize:          fc.Batch.Size,
+		FlushInterval:      time.Duration(fc.Batch.FlushInterval),
+		MaxEntryAge:        time.Duration(fc.Batch.MaxEntryAge),
+		MaxQueueSize:       fc.Batch.MaxQueueSize,
+		BlockOnFull:        fc.Batch.BlockOnFull,
+		CoalesceWindow:     time.Duration(fc.Batch.CoalesceWindow),
+		RetryCount:         fc.Retry.Count,
+		RetryBudget:        fc.Retry.Budget,
+		RateLimit:          fc.RateLimit.Entries,
+		ByteRateLimit:      fc.RateLimit.Bytes,
+		FailoverURLs:       fc.Failover.URLs,
+		FailoverThreshold:  fc.Failover.Threshold,
+		FailbackInterval:   time.Duration(fc.Failover.FailbackInterval),
+		LoadBalance:        fc.LoadBalance.Enabled,
+		ResolveInterval:    time.Duration(fc.LoadBalance.ResolveInterval),
+		RequestTimeout:     time.Duration(fc.HTTP.Timeout),
+		PushTimeout:        time.Duration(fc.HTTP.PushTimeout),
+		MaxIdleConns:       fc.HTTP.MaxIdleConns,
+		MaxConnsPerHost:    fc.HTTP.MaxConnsPerHost,
+		IdleConnTimeout:    time.Duration(fc.HTTP.IdleConnTimeout),
+		ExpectContinue:     fc.HTTP.ExpectContinue,
+		ProxyURL:           fc.HTTP.ProxyURL,
+		ReadinessProbe:     fc.Readiness.Probe,
+		ReadinessInterval:  time.Duration(fc.Readiness.Interval),
+		MaxBufferSize:      fc.Readiness.MaxBufferSize,
+		SpillDir:           fc.Spill.Dir,
+		SpillMaxBytes:      fc.Spill.MaxBytes,
+		SpillMaxAge:        time.Duration(fc.Spill.MaxAge),
+		DeadLetterFile:     fc.DeadLetterFile,
+		BreakerThreshold:   fc.Breaker.Threshold,
+		BreakerCooldown:    time.Duration(fc.Breaker.Cooldown),
+		HeartbeatInterval:  time.Duration(fc.Heartbeat.Interval),
+		Version:            fc.Heartbeat.Version,
+	}
+
+	switch fc.RateLimit.Overflow {
+	case "", "drop":
+		cfg.Overflow = OverflowDrop
+	case "block":
+		cfg.Overflow = OverflowBlock
+	default:
+		return cfg, fmt.Errorf("invalid rate_limit.overflow %q: must be drop or block", fc.RateLimit.Overflow)
+	}
+
+	if fc.Multiline.Pattern != "" {
+		re, err := regexp.Compile(fc.Multiline.Pattern)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid multiline.pattern: %w", err)
+		}
+		cfg.MultilinePattern = re
+	}
+
+	switch fc.Retry.Policy {
+	case "", "fifo":
+		cfg.RetryPolicy = RetryFIFO
+	case "newest_first":
+		cfg.RetryPolicy = RetryNewestFirst
+	default:
+		return cfg, fmt.Errorf("invalid retry.policy %q: must be fifo or newest_first", fc.Retry.Policy)
+	}
+
+	switch fc.LineSizePolicy {
+	case "", "truncate":
+		cfg.LineSizePolicy = LineTruncate
+	case "drop":
+		cfg.LineSizePolicy = LineDrop
+	default:
+		return cfg, fmt.Errorf("invalid line_size_policy %q: must be truncate or drop", fc.LineSizePolicy)
+	}
+
+	if a := fc.Batch.Adaptive; a != nil {
+		cfg.AdaptiveBatching = &AdaptiveBatching{
+			MinBatchSize:     a.MinSize,
+			MaxBatchSize:     a.MaxSize,
+			MinFlushInterval: time.Duration(a.MinFlushInterval),
+			MaxFlushInterval: time.Duration(a.MaxFlushInterval),
+		}
+	}
+
+	switch fc.Batch.By {
+	case "", "global":
+		cfg.BatchBy = BatchGlobal
+	case "level":
+		cfg.BatchBy = BatchPerLevel
+	case "stream":
+		cfg.BatchBy = BatchPerStream
+	default:
+		return cfg, fmt.Errorf("invalid batch.by %q: must be global, level or stream", fc.Batch.By)
+	}
+
+	switch fc.CancelPolicy {
+	case "", "flush":
+		cfg.CancelPolicy = CancelFlush
+	case "discard":
+		cfg.CancelPolicy = CancelDiscard
+	default:
+		return cfg, fmt.Errorf("invalid cancel_policy %q: must be flush or discard", fc.CancelPolicy)
+	}
+
+	if fc.SigV4 != nil {
+		cfg.SigV4 = &SigV4Config{Region: fc.SigV4.Region, Service: fc.SigV4.Service, Profile: fc.SigV4.Profile}
+	}
+
+	cfg.LineFormat = fc.LineFormat
+	cfg.TimeLayouts = fc.TimeLayouts
+	cfg.MaxTimestampAge = time.Duration(fc.MaxTimestampAge)
+	switch fc.TimestampPolicy {
+	case "", "event":
+		cfg.TimestampPolicy = TimestampEvent
+	case "arrival":
+		cfg.TimestampPolicy = TimestampArrival
+	default:
+		return cfg, fmt.Errorf("invalid timestamp_policy %q: must be event or arrival", fc.TimestampPolicy)
+	}
+	if fc.TimeZone != "" {
+		loc, err := time.LoadLocation(fc.TimeZone)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid time_zone: %w", err)
+		}
+		cfg.TimeLocation = loc
+	}
+
+	if fs := fc.Schema; fs != nil {
+		cfg.Schema = &Schema{Required: fs.Required, Labels: fs.Labels, Types: make(map[string]FieldType, len(fs.Types))}
+		for k, name := range fs.Types {
+			t, ok := fieldTypes[name]
+			if !ok {
+				return cfg, fmt.Errorf("invalid schema.types.%s %q: must be string, int, float, bool, duration or time", k, name)
+			}
+			cfg.Schema.Types[k] = t
+		}
+		switch fs.Policy {
+		case "", "flag":
+			cfg.Schema.Policy = SchemaFlag
+		case "fix":
+			cfg.Schema.Policy = SchemaFix
+		case "reject":
+			cfg.Schema.Policy = SchemaReject
+		default:
+			return cfg, fmt.Errorf("invalid schema.policy %q: must be flag, fix or reject", fs.Policy)
+		}
+	}
+
+	cfg.AttrRules = AttrRules{
+		Separator:    fc.Attrs.Separator,
+		Labels:       fc.Attrs.Labels,
+		Drop:         fc.Attrs.Drop,
+		ErrorDetails: fc.Attrs.ErrorDetails,
+	}
+
+	cfg.DefaultLevel = fc.LevelDetection.Default
+	cfg.LevelTokens = fc.LevelDetection.Tokens
+	cfg.LevelLabels = fc.LevelDetection.Labels
+	for i, p := range fc.LevelDetection.Patterns {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid level_detection.patterns[%d]: %w", i, err)
+		}
+		cfg.LevelPatterns = append(cfg.LevelPatterns, LevelPattern{Regexp: re, Level: p.Level})
+	}
+
+	for i, r := range fc.Routes {
+		route := Route{Level: r.Level, Labels: r.Labels, Tenant: r.Tenant, URL: r.URL}
+		if r.Message != "" {
+			re, err := regexp.Compile(r.Message)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid routes[%d].message: %w", i, err)
+			}
+			route.Message = re
+		}
+		cfg.Routes = append(cfg.Routes, route)
+	}
+	cfg.Pipeline = fc.PipelineStages
+	cfg.MaxLabelValueLength = fc.MaxLabelValueLen
+
+	if fc.OAuth2 != nil {
+		cfg.OAuth2 = &OAuth2Config{TokenURL: fc.OAuth2.TokenURL, ClientID: fc.OAuth2.ClientID, ClientSecret: fc.OAuth2.ClientSecret, Scopes: fc.OAuth2.Scopes}
+		if len(fc.OAuth2.Params) > 0 {
+			cfg.OAuth2.EndpointParams = url.Values{}
+			for k, v := range fc.OAuth2.Params {
+				cfg.OAuth2.EndpointParams.Set(k, v)
+			}
+		}
+	}
+
+	if fc.TLS != nil {
+		tlsConfig, err := fc.TLS.tlsConfig()
+		if err != nil {
+			return cfg, err
+		}
+		cfg.TLSConfig = tlsConfig
+	}
+
+	return cfg, nil
+}
+
+// tlsConfig loads the certificates referenced by the TLS section.
+func (t *FileTLSConfig) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls.ca_file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls.ca_file: no certificates found in %s", t.CAFile)
+		}
+	}
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, errors.New("tls.cert_file and tls.key_file must be set together")
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls.cert_file: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
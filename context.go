@@ -0,0 +1,222 @@
+package lokilogger
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// TraceExtractor returns the trace and span IDs of the active span in ctx, or empty strings if there is none.
+type TraceExtractor func(ctx context.Context) (traceID, spanID string)
+
+// ContextExtractor returns request-scoped values found in ctx, e.g. a request
+// ID, attached as structured metadata to the entries logged with LogCtx.
+type ContextExtractor func(ctx context.Context) map[string]string
+
+// ContextValue returns a ContextExtractor attaching ctx.Value(key) as the
+// structured metadata field name when the value is set.
+//
+//	cfg.ContextExtractors = []lokilogger.ContextExtractor{
+//		lokilogger.ContextValue("request_id", requestIDKey{}),
+//	}
+func ContextValue(name string, key any) ContextExtractor {
+	return func(ctx context.Context) map[string]string {
+		v := ctx.Value(key)
+		if v == nil {
+			return nil
+		}
+		return map[string]string{name: fmt.Sprint(v)}
+	}
+}
+
+// std is the logger created by Init.
+var std atomic.Pointer[LokiLogger]
+
+// Default returns the logger created by Init, or nil if Init has not been called.
+func Default() *LokiLogger {
+	return std.Load()
+}
+
+// LogCtx logs msg at level with attrs as structured metadata using the logger created by Init.
+func LogCtx(ctx context.Context, level, msg string, attrs ...slog.Attr) {
+	if l := Default(); l != nil {
+		l.LogCtx(ctx, level, msg, attrs...)
+	}
+}
+
+// LogCtx logs msg at level with attrs as structured metadata. The trace and
+// span IDs found in ctx by Config.TraceExtractor are attached as the trace_id
+// and span_id fields, enabling trace-to-logs navigation in Grafana.
+func (l *LokiLogger) LogCtx(ctx context.Context, level, msg string, attrs ...slog.Attr) {
+	l.logCtx(ctx, nil, level, msg, attrs...)
+}
+
+// logCtx logs msg with the extra stream labels.
+func (l *LokiLogger) logCtx(ctx context.Context, labels map[string]string, level, msg string, attrs ...slog.Attr) {
+	select {
+	case <-l.ctx.Done():
+		return
+	default:
+	}
+
+	e := Entry{
+		Time:     time.Now(),
+		Level:    strings.ToLower(level),
+		Line:     l.redact(msg),
+		Labels:   labels,
+		Metadata: make(map[string]string, len(attrs)+2),
+	}
+
+	cfg := l.config()
+	for _, a := range attrs {
+		addAttr(&e, &cfg.AttrRules, "", a)
+	}
+
+	if cfg.Caller {
+		e.Metadata[callerKey] = callerLocation()
+	}
+	l.logEntry(ctx, cfg, e)
+}
+
+// logEntry attaches the request-scoped values found in ctx to the entry and ships it.
+func (l *LokiLogger) logEntry(ctx context.Context, cfg *Config, e Entry) {
+	for _, extract := range cfg.ContextExtractors {
+		for k, v := range extract(ctx) {
+			e.Metadata[k] = v
+		}
+	}
+
+	if extract := cfg.TraceExtractor; extract != nil {
+		traceID, spanID := extract(ctx)
+		if traceID != "" {
+			e.Metadata["trace_id"] = traceID
+		}
+		if spanID != "" {
+			e.Metadata["span_id"] = spanID
+		}
+	}
+
+	l.ship(e)
+
+	l.print(e)
+}
+
+// AttrRules standardizes the keys of the attributes passed to LogCtx and Handler. The rules match the keys after
+// flattening, e.g. "http_method" for the attribute method in the group http with the "_" separator.
+type AttrRules struct {
+	// Separator joins the names of nested groups and attributes, "." by default.
+	Separator string
+	// Labels are the keys of attributes attached as stream labels instead of structured metadata, e.g.
+	// "tenant". Their values should be few, see MaxStreams.
+	Labels []string
+	// Drop are the keys of attributes or whole groups left out, e.g. "password" or "http.request".
+	Drop []string
+	// ErrorDetails adds the type, the types of the wrapped errors and the stack trace, if the error carries
+	// one like those of github.com/pkg/errors, of error attributes, e.g. err.type, err.chain and err.stack.
+	ErrorDetails bool
+}
+
+// addAttr flattens the attribute into the structured metadata or labels of the entry.
+func addAttr(e *Entry, rules *AttrRules, prefix string, a slog.Attr) {
+	key := a.Key
+	if key != "" {
+		key = prefix + key
+		if slices.Contains(rules.Drop, key) {
+			return
+		}
+	}
+
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if key != "" {
+			prefix = key + cmp.Or(rules.Separator, ".")
+		}
+		for _, ga := range v.Group() {
+			addAttr(e, rules, prefix, ga)
+		}
+		return
+	}
+
+	if key == "" {
+		return
+	}
+
+	if slices.Contains(rules.Labels, key) {
+		// The labels may be shared with a child logger.
+		e.Labels = maps.Clone(e.Labels)
+		if e.Labels == nil {
+			e.Labels = make(map[string]string)
+		}
+		e.Labels[key] = v.String()
+		return
+	}
+
+	if rules.ErrorDetails && v.Kind() == slog.KindAny {
+		if err, ok := v.Any().(error); ok {
+			addError(e.Metadata, key, cmp.Or(rules.Separator, "."), err)
+			return
+		}
+	}
+
+	e.Metadata[key] = v.String()
+}
+
+// addError adds the message, the type, the types of the wrapped errors and the innermost stack trace
+// of the error to md.
+func addError(md map[string]string, key, sep string, err error) {
+	md[key] = err.Error()
+	md[key+sep+"type"] = fmt.Sprintf("%T", err)
+
+	var types []string
+	var stack string
+	for _, err := range errorChain(err, nil) {
+		types = append(types, fmt.Sprintf("%T", err))
+		if s := stackTrace(err); s != "" {
+			stack = s
+		}
+	}
+	if len(types) > 1 {
+		md[key+sep+"chain"] = strings.Join(types, " > ")
+	}
+	if stack != "" {
+		md[key+sep+"stack"] = stack
+	}
+}
+
+// errorChain appends err and the errors it wraps to chain, depth first.
+func errorChain(err error, chain []error) []error {
+	if err == nil || len(chain) >= maxErrorChain {
+		return chain
+	}
+	chain = append(chain, err)
+
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		return errorChain(u.Unwrap(), chain)
+	case interface{ Unwrap() []error }:
+		for _, err := range u.Unwrap() {
+			chain = errorChain(err, chain)
+		}
+	}
+	return chain
+}
+
+// maxErrorChain limits the wrapped errors inspected, guarding against cyclic chains.
+const maxErrorChain = 32
+
+// stackTrace returns the stack trace of the error itself, not of the errors it wraps, formatted with
+// %+v, if it has a StackTrace method like the errors of github.com/pkg/errors.
+func stackTrace(err error) string {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%+v", m.Call(nil)[0].Interface()))
+}
@@ -0,0 +1,115 @@
+package lokilogger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// deadLetter is a line of the dead-letter file: a batch that could not be sent and the reason.
+type deadLetter struct {
+	Time    time.Time `json:"time"`
+	Error   string    `json:"error"`
+	Streams []Stream  `json:"streams"`
+}
+
+// deadLetter appends the failed batch to the dead-letter file, reporting whether it was written.
+func (l *LokiLogger) deadLetter(streams []Stream, pushErr error) bool {
+	path := l.config().DeadLetterFile
+	if path == "" {
+		return false
+	}
+
+	data, err := json.Marshal(deadLetter{Time: time.Now(), Error: pushErr.Error(), Streams: streams})
+	if err != nil {
+		l.logf("error", "Error loki dead letter: %v", err)
+		return false
+	}
+
+	l.deadMu.Lock()
+	defer l.deadMu.Unlock()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		l.logf("error", "Error loki dead letter: %v", err)
+		return false
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		l.logf("error", "Error loki dead letter: %v", err)
+		return false
+	}
+
+	return true
+}
+
+// Replay re-pushes the batches of a dead-letter file to the primary sink in
+// order. Sent batches are removed from the file, and the file is deleted once
+// all of them are sent. Replay stops at the first failure and returns its error,
+// keeping the remaining batches for a later attempt.
+func (l *LokiLogger) Replay(ctx context.Context, path string) error {
+	l.deadMu.Lock()
+	defer l.deadMu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	sink := l.sinks[0]
+	l.mu.Unlock()
+
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var dl deadLetter
+		err := json.Unmarshal(line, &dl)
+		if err != nil {
+			err = fmt.Errorf("%s:%d: %w", path, i+1, err)
+		} else {
+			err = pushSafely(ctx, sink, dl.Streams)
+		}
+
+		// Keep the batches not sent yet.
+		if err != nil {
+			if werr := writeLines(path, lines[i:]); werr != nil {
+				return werr
+			}
+			return err
+		}
+
+		l.counters.success(countEntries(dl.Streams))
+	}
+
+	return os.Remove(path)
+}
+
+// writeLines replaces the file with the lines.
+func writeLines(path string, lines [][]byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, line := range lines {
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
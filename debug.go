@@ -0,0 +1,47 @@
+package lokilogger
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// debugf writes a trace message to the internal logger when Config.Debug is set. Unlike logf the
+// traces are never shipped with SelfMonitor, as every shipped batch would trace another one.
+func (l *LokiLogger) debugf(format string, args ...any) {
+	cfg := l.config()
+	if !cfg.Debug {
+		return
+	}
+
+	fmt.Fprintln(cfg.InternalLogger, time.Now().Format("2006/01/02 15:04:05"), "loki_logger: debug:", fmt.Sprintf(format, args...))
+}
+
+// debugTransport traces the requests of the logger's HTTP client with Config.Debug.
+type debugTransport struct {
+	l    *LokiLogger
+	next http.RoundTripper
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.l.config().Debug {
+		return t.next.RoundTrip(req)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.l.debugf("%s %s: %d bytes, failed after %s: %v", req.Method, req.URL.Redacted(), req.ContentLength, time.Since(start), err)
+		return nil, err
+	}
+
+	t.l.debugf("%s %s: %d bytes, %s in %s", req.Method, req.URL.Redacted(), req.ContentLength, resp.Status, time.Since(start))
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport.
+func (t *debugTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,68 @@
+package lokilogger
+
+import (
+	"maps"
+	"strconv"
+	"time"
+)
+
+// deduper collapses identical consecutive entries seen within a time window
+// into a single entry annotated with the "repeated" structured metadata field.
+// Entries are identical with the same level, line, labels and tenant. The
+// structured metadata is not compared: the collapsed entry keeps the metadata
+// of the first one.
+type deduper struct {
+	window  time.Duration
+	pending Entry
+	count   int // Number of times the pending entry was seen, zero if there is none.
+}
+
+func newDeduper(window time.Duration) *deduper {
+	if window <= 0 {
+		return nil
+	}
+	return &deduper{window: window}
+}
+
+// push records the entry and returns the previously pending entry once a
+// different message arrives or the window expires.
+func (d *deduper) push(e Entry) (Entry, bool) {
+	if d == nil {
+		return e, true
+	}
+
+	// Entries of different streams or tenants are never collapsed, as the repeats would leak into the ones of the first.
+	if p := &d.pending; d.count > 0 && p.Level == e.Level && p.Line == e.Line && p.Tenant == e.Tenant &&
+		maps.Equal(p.Labels, e.Labels) && e.Time.Sub(p.Time) < d.window {
+		d.count++
+		return Entry{}, false
+	}
+
+	prev, ok := d.flush()
+	d.pending = e
+	d.count = 1
+
+	return prev, ok
+}
+
+// flush returns the pending entry, if any, annotated with its repeat count.
+func (d *deduper) flush() (Entry, bool) {
+	if d == nil || d.count == 0 {
+		return Entry{}, false
+	}
+
+	e := d.pending
+	if d.count > 1 {
+		md := make(map[string]string, len(e.Metadata)+1)
+		for k, v := range e.Metadata {
+			md[k] = v
+		}
+		md["repeated"] = strconv.Itoa(d.count)
+		e.Metadata = md
+	}
+
+	d.pending = Entry{}
+	d.count = 0
+
+	return e, true
+}
@@ -0,0 +1,35 @@
+package lokilogger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeduperComparesLabels(t *testing.T) {
+	d := newDeduper(time.Minute)
+	now := time.Now()
+
+	var shipped []Entry
+	for _, e := range []Entry{
+		{Time: now, Line: "retrying", Labels: map[string]string{"job": "a"}, Metadata: map[string]string{"try": "1"}},
+		{Time: now, Line: "retrying", Labels: map[string]string{"job": "a"}, Metadata: map[string]string{"try": "2"}},
+		{Time: now, Line: "retrying", Labels: map[string]string{"job": "b"}},
+	} {
+		if e, ok := d.push(e); ok {
+			shipped = append(shipped, e)
+		}
+	}
+	if e, ok := d.flush(); ok {
+		shipped = append(shipped, e)
+	}
+
+	if len(shipped) != 2 {
+		t.Fatalf("shipped %d entries, want 2", len(shipped))
+	}
+	if e := shipped[0]; e.Labels["job"] != "a" || e.Metadata["repeated"] != "2" || e.Metadata["try"] != "1" {
+		t.Errorf("shipped %+v, want job a repeated twice with the metadata of the first", e)
+	}
+	if e := shipped[1]; e.Labels["job"] != "b" || e.Metadata["repeated"] != "" {
+		t.Errorf("shipped %+v, want job b once", e)
+	}
+}
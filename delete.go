@@ -0,0 +1,90 @@
+package lokilogger
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DeleteRequest describes a log deletion request known to Loki.
+type DeleteRequest struct {
+	RequestID string
+	Query     string
+	Start     time.Time
+	End       time.Time
+	Status    string // received, processed, etc.
+	CreatedAt time.Time
+}
+
+// deleteRequestJSON is the JSON representation of a delete request; times are Unix seconds.
+type deleteRequestJSON struct {
+	RequestID string  `json:"request_id"`
+	Query     string  `json:"query"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+	Status    string  `json:"status"`
+	CreatedAt float64 `json:"created_at"`
+}
+
+// unixSeconds converts fractional Unix seconds to a time.
+func unixSeconds(sec float64) time.Time {
+	return time.Unix(0, int64(sec*float64(time.Second)))
+}
+
+// CreateDeleteRequest asks Loki to delete the log lines matching the LogQL
+// query within [start, end]. A zero end deletes up to now.
+func (c *Client) CreateDeleteRequest(ctx context.Context, logql string, start, end time.Time) error {
+	params := url.Values{
+		"query": {logql},
+		"start": {strconv.FormatInt(start.Unix(), 10)},
+	}
+	if !end.IsZero() {
+		params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	}
+
+	resp, err := c.do(ctx, "POST", "/loki/api/v1/delete", params)
+	if err != nil {
+		return err
+	}
+
+	return checkResponse(resp)
+}
+
+// ListDeleteRequests returns the delete requests known to Loki.
+func (c *Client) ListDeleteRequests(ctx context.Context) ([]DeleteRequest, error) {
+	var raw []deleteRequestJSON
+	if err := c.getJSON(ctx, "/loki/api/v1/delete", nil, &raw); err != nil {
+		return nil, err
+	}
+
+	requests := make([]DeleteRequest, 0, len(raw))
+	for _, r := range raw {
+		requests = append(requests, DeleteRequest{
+			RequestID: r.RequestID,
+			Query:     r.Query,
+			Start:     unixSeconds(r.StartTime),
+			End:       unixSeconds(r.EndTime),
+			Status:    r.Status,
+			CreatedAt: unixSeconds(r.CreatedAt),
+		})
+	}
+
+	return requests, nil
+}
+
+// CancelDeleteRequest cancels the delete request with the given ID. With force
+// the request is cancelled even if it has been partially processed.
+func (c *Client) CancelDeleteRequest(ctx context.Context, requestID string, force bool) error {
+	params := url.Values{"request_id": {requestID}}
+	if force {
+		params.Set("force", "true")
+	}
+
+	resp, err := c.do(ctx, "DELETE", "/loki/api/v1/delete", params)
+	if err != nil {
+		return err
+	}
+
+	return checkResponse(resp)
+}
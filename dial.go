@@ -0,0 +1,94 @@
+package lokilogger
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// DialFunc connects to the address on the named network, like net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// unixSocketPath returns the socket path of a unix:///path URL.
+func unixSocketPath(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "unix" {
+		return "", false
+	}
+	return u.Path, true
+}
+
+// Push paths appended to URLs without a path.
+const (
+	lokiPushPath = "/loki/api/v1/push"
+	otlpPushPath = "/v1/logs"
+)
+
+// httpURL returns the URL requests are sent to. Requests for a unix:///path URL
+// go to the push endpoint of the protocol on localhost, dialed over the socket.
+// Base URLs get the push path appended: pushPath if set, otherwise the path of
+// the protocol, following an /otlp suffix like that of Grafana Cloud OTLP gateways.
+func httpURL(rawURL string, protocol Protocol, pushPath string) string {
+	if pushPath == "" {
+		switch protocol {
+		case ProtocolOTLP:
+			pushPath = otlpPushPath
+		case ProtocolVictoriaLogs:
+			pushPath = victoriaLogsPushPath
+		default:
+			pushPath = lokiPushPath
+		}
+	}
+
+	if _, ok := unixSocketPath(rawURL); ok {
+		return "http://localhost" + pushPath
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || rawURL == "" {
+		return rawURL
+	}
+	switch path := strings.TrimSuffix(u.Path, "/"); {
+	case path == "":
+		u.Path = pushPath
+	case protocol == ProtocolOTLP && strings.HasSuffix(path, "/otlp"):
+		u.Path = path + otlpPushPath
+	default:
+		return rawURL
+	}
+	return u.String()
+}
+
+// detectProtocol returns the protocol of the endpoint of the URL, e.g. ProtocolOTLP for the base URL
+// of a Grafana Cloud OTLP gateway, or ProtocolLoki if it isn't recognized.
+func detectProtocol(rawURL string) Protocol {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ProtocolLoki
+	}
+	switch path := strings.TrimSuffix(u.Path, "/"); {
+	case strings.HasSuffix(path, "/otlp") || strings.HasSuffix(path, otlpPushPath):
+		return ProtocolOTLP
+	case strings.HasSuffix(path, victoriaLogsPushPath):
+		return ProtocolVictoriaLogs
+	}
+	return ProtocolLoki
+}
+
+// dialFunc returns the dialer of the HTTP transport: Config.DialContext, the
+// socket of a unix:///path URL, or nil for the default dialer.
+func dialFunc(cfg Config) DialFunc {
+	if cfg.DialContext != nil {
+		return cfg.DialContext
+	}
+
+	if path, ok := unixSocketPath(cfg.URL); ok {
+		var d net.Dialer
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, "unix", path)
+		}
+	}
+
+	return nil
+}
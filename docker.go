@@ -0,0 +1,279 @@
+package lokilogger
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDockerHost         = "unix:///var/run/docker.sock"
+	defaultDockerPollInterval = 5 * time.Second
+)
+
+// DockerConfig selects the containers followed by TailDocker.
+type DockerConfig struct {
+	// Host is the address of the Docker API: unix:///path or tcp://host:port. Defaults to the DOCKER_HOST
+	// environment variable or unix:///var/run/docker.sock.
+	Host string
+	// Filters select the containers as in the Docker API, e.g. {"label": {"logging=loki"}}. All running
+	// containers are followed when empty.
+	Filters map[string][]string
+	// PollInterval is the interval between checks for started containers (5s by default).
+	PollInterval time.Duration
+	// Labels are extra stream labels of the lines, in addition to container_name, image and stream.
+	Labels map[string]string
+}
+
+// dockerAPI is a minimal client of the Docker Engine API.
+type dockerAPI struct {
+	base   string
+	client *http.Client
+}
+
+func newDockerAPI(host string) (*dockerAPI, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Docker host %q: %w", host, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		var d net.Dialer
+		transport := &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, "unix", u.Path)
+		}}
+		return &dockerAPI{base: "http://docker", client: &http.Client{Transport: transport}}, nil
+	case "tcp", "http":
+		return &dockerAPI{base: "http://" + u.Host, client: &http.Client{}}, nil
+	case "https":
+		return &dockerAPI{base: "https://" + u.Host, client: &http.Client{}}, nil
+	default:
+		return nil, fmt.Errorf("invalid Docker host %q: scheme must be unix, tcp, http or https", host)
+	}
+}
+
+// get sends a GET request for the API path and returns the response, failing on non-2xx responses.
+func (d *dockerAPI) get(ctx context.Context, path string, params url.Values) (*http.Response, error) {
+	u := d.base + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, checkResponse(resp)
+	}
+	return resp, nil
+}
+
+// getJSON decodes the JSON response of the API path into v.
+func (d *dockerAPI) getJSON(ctx context.Context, path string, params url.Values, v any) error {
+	resp, err := d.get(ctx, path, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// dockerContainer is a container of the /containers/json listing.
+type dockerContainer struct {
+	ID    string   `json:"Id"`
+	Names []string `json:"Names"`
+	Image string   `json:"Image"`
+}
+
+// TailDocker follows the stdout and stderr of the running containers until ctx is done and ships their lines
+// like written ones, through the timestamp and level parsing, multiline joining and batching of the logger,
+// e.g. on small hosts without promtail or the Docker Loki logging driver. Every line carries
+// the container_name, image and stream (stdout or stderr) labels. Containers started later are picked up
+// within PollInterval; the logs written before TailDocker started are skipped.
+//
+//	go l.TailDocker(ctx, lokilogger.DockerConfig{Filters: map[string][]string{"label": {"logging=loki"}}})
+func (l *LokiLogger) TailDocker(ctx context.Context, cfg DockerConfig) error {
+	api, err := newDockerAPI(cmp.Or(cfg.Host, os.Getenv("DOCKER_HOST"), defaultDockerHost))
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	if len(cfg.Filters) > 0 {
+		filters, err := json.Marshal(cfg.Filters)
+		if err != nil {
+			return err
+		}
+		params.Set("filters", string(filters))
+	}
+
+	since := time.Now()
+	interval := cmp.Or(cfg.PollInterval, defaultDockerPollInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	followed := make(map[string]bool)
+	stopped := make(map[string]time.Time) // Time the log stream of a container ended, to resume after restarts.
+	defer wg.Wait()
+
+	for {
+		var containers []dockerContainer
+		if err := api.getJSON(ctx, "/containers/json", params, &containers); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			l.logf("warn", "Failed to list Docker containers: %v", err)
+		}
+
+		for _, c := range containers {
+			mu.Lock()
+			if followed[c.ID] {
+				mu.Unlock()
+				continue
+			}
+			followed[c.ID] = true
+			from, ok := stopped[c.ID]
+			if !ok {
+				from = since
+			}
+			mu.Unlock()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := l.followContainer(ctx, api, c, from, cfg.Labels); err != nil && ctx.Err() == nil {
+					l.logf("warn", "Failed to follow Docker container %s: %v", c.ID[:min(len(c.ID), 12)], err)
+				}
+				// The container stopped; it is followed again if it restarts.
+				mu.Lock()
+				delete(followed, c.ID)
+				stopped[c.ID] = time.Now()
+				mu.Unlock()
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// followContainer ships the log lines of the container written after since until its log stream ends.
+func (l *LokiLogger) followContainer(ctx context.Context, api *dockerAPI, c dockerContainer, since time.Time, extra map[string]string) error {
+	var inspect struct {
+		Config struct {
+			Tty bool `json:"Tty"`
+		} `json:"Config"`
+	}
+	if err := api.getJSON(ctx, "/containers/"+c.ID+"/json", nil, &inspect); err != nil {
+		return err
+	}
+
+	params := url.Values{
+		"follow": {"1"},
+		"stdout": {"1"},
+		"stderr": {"1"},
+		"since":  {strconv.FormatInt(since.Unix(), 10)},
+	}
+	resp, err := api.get(ctx, "/containers/"+c.ID+"/logs", params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	name := c.ID[:min(len(c.ID), 12)]
+	if len(c.Names) > 0 {
+		name = strings.TrimPrefix(c.Names[0], "/")
+	}
+	labels := func(stream string) map[string]string {
+		labels := make(map[string]string, len(extra)+3)
+		for k, v := range extra {
+			labels[k] = v
+		}
+		labels["container_name"] = name
+		labels["image"] = c.Image
+		labels["stream"] = stream
+		return labels
+	}
+
+	// Containers with a TTY have a single raw stream, the others multiplex stdout and stderr.
+	if inspect.Config.Tty {
+		stdout := labels("stdout")
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(nil, maxTailLine)
+		for scanner.Scan() {
+			l.shipDockerLine(scanner.Bytes(), stdout)
+		}
+		return scanner.Err()
+	}
+
+	streams := map[byte]map[string]string{1: labels("stdout"), 2: labels("stderr")}
+	partial := make(map[byte][]byte)
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(resp.Body, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		frame := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(resp.Body, frame); err != nil {
+			return err
+		}
+
+		stream, ok := streams[header[0]]
+		if !ok {
+			continue
+		}
+		// Long lines are split into several frames.
+		data := append(partial[header[0]], frame...)
+		for {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				break
+			}
+			l.shipDockerLine(data[:i], stream)
+			data = data[i+1:]
+		}
+		if len(data) > maxTailLine {
+			l.shipDockerLine(data, stream)
+			data = nil
+		}
+		partial[header[0]] = data
+	}
+}
+
+// shipDockerLine ships the log line of a container like a written one.
+func (l *LokiLogger) shipDockerLine(line []byte, labels map[string]string) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if len(line) == 0 {
+		return
+	}
+	l.collect(l.redact(string(line)), labels)
+}
@@ -0,0 +1,61 @@
+package lokilogger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// dryRunSink writes the encoded payloads to a writer instead of pushing them,
+// each preceded by a summary line with the payload size:
+//
+//	POST http://loki:3100/loki/api/v1/push: 412 bytes, 6 entries in 3 streams
+//	{"streams":[...]}
+type dryRunSink struct {
+	mu       sync.Mutex
+	w        io.Writer
+	url      string
+	protocol Protocol
+}
+
+// Push implements Sink.
+func (s *dryRunSink) Push(ctx context.Context, streams []Stream) error {
+	var payload []byte
+	if s.protocol == ProtocolOTLP {
+		payload = encodeOTLP(streams)
+	} else {
+		buf := bufPool.Get().(*bytes.Buffer)
+		defer bufPool.Put(buf)
+		buf.Reset()
+		if s.protocol == ProtocolVictoriaLogs {
+			encodeVictoriaLogs(buf, streams)
+			buf.Truncate(buf.Len() - 1)
+		} else {
+			encodeLokiJSON(buf, streams)
+		}
+		payload = buf.Bytes()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	url := s.url
+	if url == "" {
+		url = "Loki"
+	}
+
+	if tenant := streamsTenant(streams); tenant != "" {
+		url += " (tenant " + tenant + ")"
+	}
+
+	_, err := fmt.Fprintf(s.w, "POST %s: %d bytes, %d entries in %d streams\n", url, len(payload), countEntries(streams), len(streams))
+	if err != nil || s.protocol == ProtocolOTLP {
+		// The protobuf payload isn't readable, so only its size is reported.
+		return err
+	}
+
+	_, err = fmt.Fprintf(s.w, "%s\n", payload)
+	return err
+}
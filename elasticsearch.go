@@ -0,0 +1,167 @@
+package lokilogger
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ElasticsearchSink indexes entries with the _bulk API of Elasticsearch or OpenSearch. Every entry becomes
+// a document with the @timestamp, message and log.level fields, the stream labels under labels and the
+// structured metadata under metadata:
+//
+//	{"@timestamp":"2024-01-02T15:04:05.123456789Z","message":"line","log":{"level":"info"},"labels":{"service_name":"api"}}
+//
+// Documents get IDs derived from their content and are created rather than indexed, so a batch retried
+// after a partial failure doesn't duplicate the documents already stored.
+type ElasticsearchSink struct {
+	URL string // Base URL of the cluster, e.g. http://elasticsearch:9200.
+	// Index is the name of the index or data stream. With IndexDateLayout the date of the entry in UTC
+	// is appended, e.g. Index "logs-" and IndexDateLayout "2006.01.02" write to daily logs-2024.01.02 indices.
+	Index           string
+	IndexDateLayout string
+	// Username and Password authenticate with basic authentication, APIKey with an Elasticsearch API key.
+	Username string
+	Password string
+	APIKey   string
+	Client   *http.Client
+}
+
+// Push implements Sink. Documents rejected because of overload or server errors fail the push with a
+// retryable error; other rejections, e.g. mapping conflicts, are permanent.
+func (s *ElasticsearchSink) Push(ctx context.Context, streams []Stream) error {
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	s.encode(buf, streams)
+	size := buf.Len()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(s.URL, "/")+"/_bulk", newPooledBody(buf))
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = int64(size)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case s.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+s.APIKey)
+	case s.Username != "":
+		req.SetBasicAuth(s.Username, s.Password)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return checkResponse(resp)
+	}
+	defer resp.Body.Close()
+
+	return bulkError(resp.Body)
+}
+
+// encode writes the create actions and documents of the entries as NDJSON.
+func (s *ElasticsearchSink) encode(buf *bytes.Buffer, streams []Stream) {
+	for _, st := range streams {
+		key := labelsKey(st.Labels)
+
+		for i, e := range st.Entries {
+			index := s.Index
+			if s.IndexDateLayout != "" {
+				index += e.Time.UTC().Format(s.IndexDateLayout)
+			}
+
+			buf.WriteString(`{"create":{"_index":`)
+			writeJSONString(buf, index)
+			buf.WriteString(`,"_id":"`)
+			buf.WriteString(documentID(key, i, e))
+			buf.WriteString("\"}}\n")
+
+			buf.WriteString(`{"@timestamp":"`)
+			buf.Write(e.Time.UTC().AppendFormat(buf.AvailableBuffer(), time.RFC3339Nano))
+			buf.WriteString(`","message":`)
+			writeJSONString(buf, e.Line)
+			buf.WriteString(`,"log":{"level":`)
+			writeJSONString(buf, e.Level)
+			buf.WriteString(`},"labels":`)
+			writeJSONObject(buf, st.Labels)
+			if len(e.Metadata) > 0 {
+				buf.WriteString(`,"metadata":`)
+				writeJSONObject(buf, e.Metadata)
+			}
+			buf.WriteString("}\n")
+		}
+	}
+}
+
+// documentID returns the ID of the i-th entry of the stream with the labels key, stable across retries of the batch.
+func documentID(key string, i int, e Entry) string {
+	h := sha256.New()
+	h.Write([]byte(key))
+	h.Write(strconv.AppendInt(nil, e.Time.UnixNano(), 10))
+	h.Write(strconv.AppendInt(nil, int64(i), 10))
+	h.Write([]byte(e.Line))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// bulkError returns an error describing the documents rejected in the _bulk response, if any.
+// Documents that already exist count as stored.
+func bulkError(body io.Reader) error {
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return fmt.Errorf("bulk: decode response: %w", err)
+	}
+	if !resp.Errors {
+		return nil
+	}
+
+	var failed, retry int
+	var reason string
+	for _, item := range resp.Items {
+		for _, r := range item {
+			if r.Status < 300 || r.Status == http.StatusConflict {
+				continue
+			}
+			failed++
+			if r.Status == http.StatusTooManyRequests || r.Status >= 500 {
+				retry++
+			}
+			if reason == "" {
+				reason = r.Error.Type + ": " + r.Error.Reason
+			}
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+
+	msg := fmt.Sprintf("bulk: %d of %d documents rejected: %s", failed, len(resp.Items), reason)
+	if retry > 0 {
+		// Retrying the whole batch is safe, as the stored documents are skipped as conflicts.
+		return &PushError{StatusCode: http.StatusServiceUnavailable, Body: msg}
+	}
+	return &PushError{StatusCode: http.StatusBadRequest, Body: msg}
+}
@@ -0,0 +1,130 @@
+package lokilogger
+
+import (
+	"bytes"
+	"strconv"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// encodeLokiJSON writes the Loki push API payload of the streams to buf
+// incrementally, without building an intermediate representation:
+//
+//	{"streams":[{"stream":{"level":"info"},"values":[["<ns>","line",{"key":"value"}]]}]}
+func encodeLokiJSON(buf *bytes.Buffer, streams []Stream) {
+	buf.WriteString(`{"streams":[`)
+
+	for i, s := range streams {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		buf.WriteString(`{"stream":`)
+		writeJSONObject(buf, s.Labels)
+		buf.WriteString(`,"values":[`)
+
+		for j, e := range s.Entries {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+
+			buf.WriteString(`["`)
+			buf.Write(strconv.AppendInt(buf.AvailableBuffer(), e.Time.UnixNano(), 10))
+			buf.WriteString(`",`)
+			writeJSONString(buf, e.Line)
+			if len(e.Metadata) > 0 {
+				buf.WriteByte(',')
+				writeJSONObject(buf, e.Metadata)
+			}
+			buf.WriteByte(']')
+		}
+
+		buf.WriteString(`]}`)
+	}
+
+	buf.WriteString(`]}`)
+}
+
+// writeJSONObject writes the map as a JSON object with sorted keys.
+func writeJSONObject(buf *bytes.Buffer, m map[string]string) {
+	buf.WriteByte('{')
+	for i, k := range sortedKeys(m) {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeJSONString(buf, k)
+		buf.WriteByte(':')
+		writeJSONString(buf, m[k])
+	}
+	buf.WriteByte('}')
+}
+
+// writeJSONString writes s as a JSON string, replacing invalid UTF-8 with U+FFFD like encoding/json.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+
+	buf.WriteByte('"')
+
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+
+			buf.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[c>>4])
+				buf.WriteByte(hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`�`)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
+
+// pooledBody is a request body reading a pooled buffer, which is returned to
+// the pool once the transport closes the body.
+type pooledBody struct {
+	*bytes.Reader
+	buf    *bytes.Buffer
+	closed atomic.Bool
+}
+
+func newPooledBody(buf *bytes.Buffer) *pooledBody {
+	return &pooledBody{Reader: bytes.NewReader(buf.Bytes()), buf: buf}
+}
+
+func (b *pooledBody) Close() error {
+	if b.closed.CompareAndSwap(false, true) {
+		bufPool.Put(b.buf)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package lokilogger
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// hostLabels returns labels identifying the host, process and binary build.
+func hostLabels() map[string]string {
+	labels := map[string]string{
+		"pid":        strconv.Itoa(os.Getpid()),
+		"go_version": runtime.Version(),
+	}
+
+	if host, err := os.Hostname(); err == nil {
+		labels["host"] = host
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		labels["build_path"] = info.Main.Path
+		if info.Main.Version != "" {
+			labels["build_version"] = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				labels["vcs_revision"] = s.Value
+			}
+		}
+	}
+
+	return labels
+}
+
+// envLabels returns the non-empty environment variables as labels named after
+// the lower-cased variable name, e.g. APP_ENV becomes app_env.
+func envLabels(names []string) map[string]string {
+	labels := make(map[string]string, len(names))
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			labels[strings.ToLower(name)] = v
+		}
+	}
+	return labels
+}
+
+// staticLabels merges the labels attached to every stream of the logger.
+func staticLabels(cfg Config) map[string]string {
+	labels := make(map[string]string)
+	if cfg.HostLabels {
+		for k, v := range hostLabels() {
+			labels[k] = v
+		}
+	}
+	for k, v := range envLabels(cfg.EnvLabels) {
+		labels[k] = v
+	}
+	for k, v := range cfg.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// sanitizeLabelName replaces characters that are not allowed in Loki label names with underscores.
+func sanitizeLabelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		b[i] = '_'
+	}
+	return string(b)
+}
@@ -0,0 +1,100 @@
+package lokilogger
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigFromEnv returns a configuration read from the environment:
+//
+//	LOKI_URL               Loki push API or base URL
+//	LOKI_NAME              service name
+//	LOKI_TENANT            tenant ID sent as X-Scope-OrgID
+//	LOKI_ACCESS_TOKEN      bearer token
+//	LOKI_ACCESS_TOKEN_FILE file holding the bearer token, re-read when it rotates
+//	LOKI_BATCH_SIZE        number of logs per batch
+//	LOKI_FLUSH_INTERVAL    flush interval, e.g. 5s
+//	LOKI_RETRY_COUNT       number of push attempts
+//	LOKI_PROTOCOL          loki, otlp or victorialogs
+//	LOKI_PUSH_PATH         path appended to a base URL
+//	LOKI_LABELS            static labels, e.g. env=prod,team=core
+//	LOKI_FAILOVER_URLS     comma separated secondary URLs
+//	LOKI_READINESS_PROBE   true to hold logs until Loki is ready
+//	LOKI_MAX_BUFFER_SIZE   number of entries held while Loki is not ready
+//	LOKI_MIN_LEVEL         minimum level: debug, info, warn or error
+//
+// Unset variables keep their zero values, so the usual defaults apply.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		URL:             os.Getenv("LOKI_URL"),
+		Name:            os.Getenv("LOKI_NAME"),
+		TenantID:        os.Getenv("LOKI_TENANT"),
+		AccessToken:     os.Getenv("LOKI_ACCESS_TOKEN"),
+		AccessTokenFile: os.Getenv("LOKI_ACCESS_TOKEN_FILE"),
+		Protocol:        Protocol(os.Getenv("LOKI_PROTOCOL")),
+		PushPath:        os.Getenv("LOKI_PUSH_PATH"),
+		MinLevel:        os.Getenv("LOKI_MIN_LEVEL"),
+	}
+
+	var err error
+	if cfg.BatchSize, err = envInt("LOKI_BATCH_SIZE"); err != nil {
+		return cfg, err
+	}
+	if cfg.RetryCount, err = envInt("LOKI_RETRY_COUNT"); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxBufferSize, err = envInt("LOKI_MAX_BUFFER_SIZE"); err != nil {
+		return cfg, err
+	}
+
+	if v := os.Getenv("LOKI_FLUSH_INTERVAL"); v != "" {
+		if cfg.FlushInterval, err = time.ParseDuration(v); err != nil {
+			return cfg, fmt.Errorf("invalid LOKI_FLUSH_INTERVAL %q: %w", v, err)
+		}
+	}
+
+	if v := os.Getenv("LOKI_READINESS_PROBE"); v != "" {
+		if cfg.ReadinessProbe, err = strconv.ParseBool(v); err != nil {
+			return cfg, fmt.Errorf("invalid LOKI_READINESS_PROBE %q: %w", v, err)
+		}
+	}
+
+	if v := os.Getenv("LOKI_LABELS"); v != "" {
+		cfg.Labels = make(map[string]string)
+		for _, pair := range strings.Split(v, ",") {
+			k, val, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(k) == "" {
+				return cfg, fmt.Errorf("invalid LOKI_LABELS %q: expected key=value pairs", v)
+			}
+			cfg.Labels[strings.TrimSpace(k)] = strings.TrimSpace(val)
+		}
+	}
+
+	if v := os.Getenv("LOKI_FAILOVER_URLS"); v != "" {
+		for _, u := range strings.Split(v, ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				cfg.FailoverURLs = append(cfg.FailoverURLs, u)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+// envInt returns the integer value of the environment variable, or zero if it is unset.
+func envInt(name string) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, v, err)
+	}
+
+	return n, nil
+}
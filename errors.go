@@ -0,0 +1,110 @@
+package lokilogger
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Causes of failed pushes, matched with errors.Is against the errors passed to Config.OnError and in
+// Stats.LastError, e.g. to alert on authentication failures differently than on capacity issues.
+var (
+	// ErrRateLimited matches pushes rejected with 429 Too Many Requests, e.g. by the ingestion or per stream
+	// rate limits of the tenant.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrEntryTooOld matches pushes rejected because entries are older than reject_old_samples_max_age.
+	ErrEntryTooOld = errors.New("entry too old")
+	// ErrLineTooLong matches pushes rejected because lines exceed max_line_size.
+	ErrLineTooLong = errors.New("line too long")
+	// ErrUnauthorized matches pushes rejected with 401 Unauthorized or 403 Forbidden.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrUnavailable matches pushes failed with a server error or without reaching the server.
+	ErrUnavailable = errors.New("unavailable")
+)
+
+const (
+	// maxErrorBody is the number of bytes of an error response kept in PushError.Body.
+	maxErrorBody = 16 << 10
+	// maxErrorExcerpt is the number of bytes of the body in the message of a PushError.
+	maxErrorExcerpt = 256
+)
+
+// traceHeaders are the response headers carrying a trace or request ID, in order of preference.
+var traceHeaders = []string{"Traceparent", "X-Trace-Id", "Uber-Trace-Id", "X-Amzn-Trace-Id", "X-Request-Id"}
+
+// PushError is a push rejected by the server with a non-2xx status, passed to Config.OnError and in
+// Stats.LastError. Use errors.As to inspect it and errors.Is to match its cause, e.g. ErrRateLimited.
+type PushError struct {
+	StatusCode int
+	// TraceID is the trace or request ID of the response, e.g. of the Traceparent or X-Request-Id header,
+	// to find the request in the logs and traces of Loki or a gateway.
+	TraceID string
+	// Body is the start of the response body, at most 16 KiB; Truncated reports whether it was cut.
+	Body      string
+	Truncated bool
+}
+
+// Error returns the status, trace ID and the first line of the body, shortened to keep diagnostics readable.
+func (e *PushError) Error() string {
+	msg := fmt.Sprintf("unexpected status code %d", e.StatusCode)
+	if e.TraceID != "" {
+		msg += " (trace ID " + e.TraceID + ")"
+	}
+
+	excerpt, _, cut := strings.Cut(strings.TrimSpace(e.Body), "\n")
+	if len(excerpt) > maxErrorExcerpt {
+		excerpt, cut = truncateLine(excerpt, maxErrorExcerpt), true
+	}
+	if cut || e.Truncated {
+		excerpt += " ..."
+	}
+	return msg + ": " + excerpt
+}
+
+// Is matches the error against the causes of failed pushes.
+func (e *PushError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrUnavailable:
+		return e.StatusCode >= 500
+	case ErrEntryTooOld:
+		return e.StatusCode == http.StatusBadRequest && tooOldRe.MatchString(e.Body)
+	case ErrLineTooLong:
+		return e.StatusCode == http.StatusBadRequest && lineTooLongRe.MatchString(e.Body)
+	}
+	return false
+}
+
+// traceID returns the trace or request ID of the response headers.
+func traceID(h http.Header) string {
+	for _, name := range traceHeaders {
+		if v := h.Get(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// transportError is a push failed without a response of the server.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+func (e *transportError) Is(target error) bool { return target == ErrUnavailable }
+
+// classify wraps the errors of pushes that didn't reach the server, so that they match ErrUnavailable.
+func classify(err error) error {
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return &transportError{err: err}
+	}
+	return err
+}
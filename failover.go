@@ -0,0 +1,95 @@
+package lokilogger
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailoverThreshold = 3
+	defaultFailbackInterval  = 30 * time.Second
+)
+
+// FailoverSink pushes to the first (primary) sink and fails over to the next
+// one after threshold consecutive failures. While a secondary is active, the
+// primary is probed with a regular push every failback interval and becomes
+// active again once it succeeds.
+type FailoverSink struct {
+	sinks     []Sink
+	threshold int
+	failback  time.Duration
+
+	mu         sync.Mutex
+	active     int
+	failures   int
+	switchedAt time.Time
+}
+
+// NewFailoverSink returns a FailoverSink over sinks ordered by priority.
+// Zero threshold and failback use the defaults of 3 failures and 30 seconds.
+func NewFailoverSink(threshold int, failback time.Duration, sinks ...Sink) *FailoverSink {
+	if threshold <= 0 {
+		threshold = defaultFailoverThreshold
+	}
+	if failback <= 0 {
+		failback = defaultFailbackInterval
+	}
+
+	return &FailoverSink{sinks: sinks, threshold: threshold, failback: failback}
+}
+
+// Active returns the index of the sink currently receiving pushes.
+func (s *FailoverSink) Active() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+// Push implements Sink.
+func (s *FailoverSink) Push(ctx context.Context, streams []Stream) error {
+	s.mu.Lock()
+	active := s.active
+	probe := active != 0 && time.Since(s.switchedAt) >= s.failback
+	s.mu.Unlock()
+
+	if probe {
+		if err := s.sinks[0].Push(ctx, streams); err == nil {
+			s.mu.Lock()
+			s.active, s.failures = 0, 0
+			s.mu.Unlock()
+			return nil
+		}
+
+		s.mu.Lock()
+		s.switchedAt = time.Now()
+		s.mu.Unlock()
+	}
+
+	for {
+		err := s.sinks[active].Push(ctx, streams)
+
+		s.mu.Lock()
+		if err == nil {
+			s.failures = 0
+			s.mu.Unlock()
+			return nil
+		}
+
+		// Permanent errors are caused by the payload, not the endpoint.
+		if !retryable(err) || active != s.active {
+			s.mu.Unlock()
+			return err
+		}
+
+		s.failures++
+		if s.failures < s.threshold || active == len(s.sinks)-1 {
+			s.mu.Unlock()
+			return err
+		}
+
+		active++
+		s.active, s.failures, s.switchedAt = active, 0, time.Now()
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,260 @@
+package lokilogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultTailPollInterval = time.Second
+	// maxTailLine ships a line without its end once it grows beyond the size, e.g. for binary files.
+	maxTailLine = 1 << 20
+)
+
+// FileTailConfig selects the files followed by TailFiles.
+type FileTailConfig struct {
+	// Paths are glob patterns of the files, e.g. /var/log/nginx/*.log. They are expanded again every
+	// PollInterval, so files created later are picked up.
+	Paths []string
+	// PositionsFile stores the offsets of the files, so that a restart resumes where it stopped.
+	PositionsFile string
+	// PollInterval is the interval between checks for new lines, files and rotations (1s by default).
+	PollInterval time.Duration
+	// Labels are extra stream labels of the lines, in addition to the filename label.
+	Labels map[string]string
+}
+
+// tailedFile is a file followed by TailFiles.
+type tailedFile struct {
+	f       *os.File
+	info    os.FileInfo
+	offset  int64
+	partial []byte // Start of a line without its end yet.
+	labels  map[string]string
+}
+
+// TailFiles follows the files until ctx is done and ships their lines like written ones, through the
+// timestamp and level parsing, multiline joining and batching of the logger, e.g. to ship the logs of a
+// third-party process without a sidecar. Every line carries the path of its file as the filename label.
+// Files are read from their start unless PositionsFile holds their offset; rotated files are read to their
+// end before following the new file, and truncated files are read again from their start.
+//
+//	go l.TailFiles(ctx, lokilogger.FileTailConfig{Paths: []string{"/var/log/nginx/*.log"}, PositionsFile: "/var/lib/myapp/positions.json"})
+func (l *LokiLogger) TailFiles(ctx context.Context, cfg FileTailConfig) error {
+	for _, pattern := range cfg.Paths {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return err
+		}
+	}
+
+	positions, err := readPositions(cfg.PositionsFile)
+	if err != nil {
+		return err
+	}
+
+	files := make(map[string]*tailedFile)
+	defer func() {
+		for _, tf := range files {
+			tf.f.Close()
+		}
+	}()
+
+	interval := cfg.PollInterval
+	if interval <= 0 {
+		interval = defaultTailPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		l.pollFiles(cfg, files, positions)
+
+		if cfg.PositionsFile != "" {
+			if err := writePositions(cfg.PositionsFile, files); err != nil {
+				l.logf("warn", "Failed to store the tail positions: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollFiles opens the new files matching the patterns, ships the lines appended to all files and handles
+// rotated, truncated and removed files.
+func (l *LokiLogger) pollFiles(cfg FileTailConfig, files map[string]*tailedFile, positions map[string]int64) {
+	matched := make(map[string]bool)
+	for _, pattern := range cfg.Paths {
+		paths, _ := filepath.Glob(pattern)
+		for _, path := range paths {
+			matched[path] = true
+		}
+	}
+
+	for path := range matched {
+		if _, ok := files[path]; ok {
+			continue
+		}
+		tf, err := openTailed(path, positions[path])
+		if err != nil {
+			l.logf("warn", "Failed to tail %s: %v", path, err)
+			continue
+		}
+		tf.labels = maps.Clone(cfg.Labels)
+		if tf.labels == nil {
+			tf.labels = make(map[string]string, 1)
+		}
+		tf.labels["filename"] = path
+		files[path] = tf
+		delete(positions, path)
+	}
+
+	for path, tf := range files {
+		l.readTailed(tf)
+
+		info, err := os.Stat(path)
+		switch {
+		case err != nil || !matched[path]:
+			// The file was removed after its last lines were read.
+			l.flushTailed(tf)
+			tf.f.Close()
+			delete(files, path)
+		case !os.SameFile(info, tf.info):
+			// The file was rotated; the new one is read from its start.
+			l.flushTailed(tf)
+			tf.f.Close()
+			if next, err := openTailed(path, 0); err == nil {
+				next.labels = tf.labels
+				files[path] = next
+				l.readTailed(next)
+			} else {
+				delete(files, path)
+			}
+		case info.Size() < tf.offset:
+			// The file was truncated, e.g. by copytruncate rotation.
+			tf.offset, tf.partial = 0, nil
+			tf.f.Seek(0, io.SeekStart)
+			l.readTailed(tf)
+		}
+	}
+}
+
+// openTailed opens the file at path positioned at the offset, or at its start if it is shorter.
+func openTailed(path string, offset int64) (*tailedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if offset > info.Size() {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &tailedFile{f: f, info: info, offset: offset}, nil
+}
+
+// readTailed ships the complete lines appended to the file since the last read.
+func (l *LokiLogger) readTailed(tf *tailedFile) {
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := tf.f.Read(buf)
+		data := buf[:n]
+
+		for len(data) > 0 {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				tf.partial = append(tf.partial, data...)
+				break
+			}
+			line := append(tf.partial, data[:i]...)
+			tf.partial = nil
+			data = data[i+1:]
+			tf.offset += int64(len(line)) + 1
+			l.shipTailed(tf, line)
+		}
+
+		if len(tf.partial) > maxTailLine {
+			l.flushTailed(tf)
+		}
+		if n == 0 || err != nil {
+			return
+		}
+	}
+}
+
+// flushTailed ships the pending line without its end.
+func (l *LokiLogger) flushTailed(tf *tailedFile) {
+	if len(tf.partial) == 0 {
+		return
+	}
+	tf.offset += int64(len(tf.partial))
+	l.shipTailed(tf, tf.partial)
+	tf.partial = nil
+}
+
+// shipTailed ships the line of the file like a written one.
+func (l *LokiLogger) shipTailed(tf *tailedFile, line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if len(line) == 0 {
+		return
+	}
+	l.collect(l.redact(string(line)), tf.labels)
+}
+
+// readPositions returns the file offsets stored in the positions file.
+func readPositions(path string) (map[string]int64, error) {
+	positions := make(map[string]int64)
+	if path == "" {
+		return positions, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return positions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &positions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// writePositions atomically replaces the positions file with the offsets of the files.
+func writePositions(path string, files map[string]*tailedFile) error {
+	positions := make(map[string]int64, len(files))
+	for p, tf := range files {
+		positions[p] = tf.offset
+	}
+
+	data, err := json.Marshal(positions)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,39 @@
+package lokilogger
+
+import (
+	"cmp"
+	"runtime/debug"
+	"time"
+)
+
+// heartbeatLoop ships an "alive" entry in the component=heartbeat stream every HeartbeatInterval until the logger stops.
+// The entries bypass the filters of written entries, so sampling or MinLevel can't mute them.
+func (l *LokiLogger) heartbeatLoop() {
+	cfg := l.config()
+	start := time.Now()
+	labels := map[string]string{"component": "heartbeat", "version": cmp.Or(cfg.Version, buildVersion(), "unknown")}
+
+	ticker := time.NewTicker(cfg.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// The uptime changes with every entry, so it is structured metadata rather than a label creating a new stream.
+		uptime := time.Since(start).Round(time.Second).String()
+		l.enqueue(Entry{Time: l.config().Clock.Now(), Level: "info", Line: "alive", Labels: labels, Metadata: map[string]string{"uptime": uptime}})
+	}
+}
+
+// buildVersion returns the module version of the binary, if it was built from a tagged module.
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "(devel)" {
+		return ""
+	}
+	return info.Main.Version
+}
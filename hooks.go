@@ -0,0 +1,32 @@
+package lokilogger
+
+// FailedPush describes a failed push of a batch to a sink, passed to Config.OnError.
+type FailedPush struct {
+	// Sink is nil for entries rejected before reaching a sink, i.e. by a Schema with SchemaReject, whose
+	// Attempt is 0.
+	Sink    Sink
+	BatchID string // Fingerprint of the batch with BatchIDs, empty otherwise.
+	Entries int    // Number of entries in the batch.
+	Attempt int
+	// Final reports whether the batch is not retried by this push anymore. It may still be
+	// held, spilled or written to the dead letter file.
+	Final bool
+	Err   error
+}
+
+// onError passes the failed push to the OnError hook if configured.
+func (l *LokiLogger) onError(sink Sink, streams []Stream, attempt int, final bool, err error) {
+	hook := l.config().OnError
+	if hook == nil {
+		return
+	}
+
+	hook(FailedPush{
+		Sink:    sink,
+		BatchID: streamsBatchID(streams),
+		Entries: countEntries(streams),
+		Attempt: attempt,
+		Final:   final,
+		Err:     err,
+	})
+}
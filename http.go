@@ -0,0 +1,102 @@
+package lokilogger
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// AccessLog returns an http.Handler middleware logging every request with the
+// method, path, status, latency, response bytes and remote address as
+// structured metadata. The entries are shipped in the stream labeled
+// log_type=access at error level for 5xx, warn for 4xx and info otherwise.
+// Context extractors and trace IDs of the request context are attached as
+// for LogCtx.
+//
+//	http.ListenAndServe(":8080", l.AccessLog(mux))
+func (l *LokiLogger) AccessLog(next http.Handler) http.Handler {
+	return accessLog(l, nil, next)
+}
+
+// AccessLog returns the access logging middleware of LokiLogger.AccessLog with the labels of the child.
+func (c *Child) AccessLog(next http.Handler) http.Handler {
+	return accessLog(c.l, c.labels, next)
+}
+
+func accessLog(l *LokiLogger, labels map[string]string, next http.Handler) http.Handler {
+	labels = mergeLabels(labels, map[string]string{"log_type": "access"})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rw, r)
+
+		latency := time.Since(start)
+
+		level := "info"
+		switch {
+		case rw.status >= 500:
+			level = "error"
+		case rw.status >= 400:
+			level = "warn"
+		}
+
+		l.logCtx(r.Context(), labels, level,
+			fmt.Sprintf("%s %s %d %s", r.Method, r.URL.Path, rw.status, latency),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rw.status),
+			slog.String("latency", latency.String()),
+			slog.Int64("bytes", rw.bytes),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("user_agent", r.UserAgent()),
+		)
+	})
+}
+
+// responseWriter records the status code and the number of bytes written.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher for streaming handlers.
+func (w *responseWriter) Flush() {
+	w.wroteHeader = true
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker for websocket upgrades, failing with http.ErrNotSupported if the wrapped
+// ResponseWriter can't be hijacked. A hijacked request is logged with status 101 unless a status was written.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && !w.wroteHeader {
+		w.status, w.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the wrapped ResponseWriter for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,75 @@
+package lokilogger
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLogHijack(t *testing.T) {
+	withoutStdout(t)
+
+	sink := entrySink{entries: make(chan Entry, 1)}
+	l := newTestLogger(t, context.Background(), Config{Sink: sink})
+
+	logged := make(chan struct{})
+	h := l.AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		rw.Flush()
+	}))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		close(logged)
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n"))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("got status %d, want 101", resp.StatusCode)
+	}
+
+	<-logged
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if e := <-sink.entries; e.Metadata["status"] != "101" {
+		t.Errorf("logged status %q, want 101", e.Metadata["status"])
+	}
+}
+
+func TestAccessLogFlush(t *testing.T) {
+	withoutStdout(t)
+
+	l := newTestLogger(t, context.Background(), Config{Sink: nopSink{}})
+	defer l.Close(context.Background())
+
+	rec := httptest.NewRecorder()
+	l.AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("chunk"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Error(err)
+		}
+	})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !rec.Flushed {
+		t.Error("response not flushed")
+	}
+}
@@ -0,0 +1,70 @@
+package lokilogger
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// KafkaMessage is a record produced by KafkaSink.
+type KafkaMessage struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// KafkaProducer produces records to Kafka, e.g. lokikafka.NewFranzProducer wrapping a franz-go client, or
+// an adapter for another client such as sarama. Produce returns once all messages
+// are acknowledged or fails if any of them isn't.
+type KafkaProducer interface {
+	Produce(ctx context.Context, msgs []KafkaMessage) error
+}
+
+// KafkaSink produces every entry as a JSON record to a Kafka topic, for pipelines where a consumer pushes
+// to Loki:
+//
+//	{"time":"2024-01-02T15:04:05.123456789Z","level":"info","line":"...","labels":{"service_name":"api"}}
+//
+// The message key is made of the values of KeyLabels, or of all stream labels if empty, so the entries
+// of a stream keep their order within a partition. The tenant of a stream is sent in the X-Scope-OrgID header.
+type KafkaSink struct {
+	Producer  KafkaProducer
+	Topic     string
+	KeyLabels []string
+}
+
+// Push implements Sink.
+func (s *KafkaSink) Push(ctx context.Context, streams []Stream) error {
+	msgs := make([]KafkaMessage, 0, countEntries(streams))
+	for _, st := range streams {
+		key := []byte(s.key(st.Labels))
+		var headers map[string]string
+		if st.Tenant != "" {
+			headers = map[string]string{"X-Scope-OrgID": st.Tenant}
+		}
+
+		for _, r := range toJSONRecords([]Stream{st}) {
+			value, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, KafkaMessage{Topic: s.Topic, Key: key, Value: value, Headers: headers})
+		}
+	}
+
+	return s.Producer.Produce(ctx, msgs)
+}
+
+// key returns the message key of the stream with the labels.
+func (s *KafkaSink) key(labels map[string]string) string {
+	if len(s.KeyLabels) == 0 {
+		return labelsKey(labels)
+	}
+
+	values := make([]string, len(s.KeyLabels))
+	for i, name := range s.KeyLabels {
+		values[i] = labels[name]
+	}
+	return strings.Join(values, "/")
+}
@@ -0,0 +1,96 @@
+package lokilogger
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultPodLabelsFile is the usual mount path of the pod labels exposed through a Downward API volume.
+	DefaultPodLabelsFile = "/etc/podinfo/labels"
+
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// KubernetesLabels returns the pod name, namespace, node and pod labels exposed
+// through the Downward API. The pod, namespace and node are read from the
+// POD_NAME, POD_NAMESPACE and NODE_NAME environment variables, falling back to
+// HOSTNAME and the service account namespace. Pod labels are read from
+// labelsFile, or DefaultPodLabelsFile when empty, and sanitized into valid
+// Loki label names. The result can be used as Config.Labels or attached as
+// structured metadata with StaticMetadata.
+func KubernetesLabels(labelsFile string) map[string]string {
+	labels := make(map[string]string)
+
+	if labelsFile == "" {
+		labelsFile = DefaultPodLabelsFile
+	}
+	for k, v := range readPodLabels(labelsFile) {
+		labels[sanitizeLabelName(k)] = v
+	}
+
+	pod := os.Getenv("POD_NAME")
+	if pod == "" {
+		pod = os.Getenv("HOSTNAME")
+	}
+	if pod != "" {
+		labels["pod"] = pod
+	}
+
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		if b, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+			namespace = strings.TrimSpace(string(b))
+		}
+	}
+	if namespace != "" {
+		labels["namespace"] = namespace
+	}
+
+	if node := os.Getenv("NODE_NAME"); node != "" {
+		labels["node"] = node
+	}
+
+	return labels
+}
+
+// readPodLabels parses the key="value" lines of a Downward API labels file.
+func readPodLabels(path string) map[string]string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	labels := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		if uv, err := strconv.Unquote(v); err == nil {
+			v = uv
+		}
+		labels[k] = v
+	}
+
+	return labels
+}
+
+// StaticMetadata returns a Middleware attaching the given structured metadata to every entry.
+func StaticMetadata(md map[string]string) Middleware {
+	return func(e Entry) (Entry, bool) {
+		merged := make(map[string]string, len(e.Metadata)+len(md))
+		for k, v := range md {
+			merged[k] = v
+		}
+		for k, v := range e.Metadata {
+			merged[k] = v
+		}
+		e.Metadata = merged
+		return e, true
+	}
+}
@@ -0,0 +1,92 @@
+package lokilogger
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// defaultMaxLabelValueLength is Loki's default max_label_value_length.
+	defaultMaxLabelValueLength = 2048
+	// defaultMaxLabels is Loki's default max_label_names_per_series.
+	defaultMaxLabels = 15
+)
+
+// validLabelName reports whether Loki accepts the label name, i.e. whether it matches [a-zA-Z_][a-zA-Z0-9_]*.
+func validLabelName(name string) bool {
+	return name != "" && sanitizeLabelName(name) == name
+}
+
+// validateLabels checks the static labels against the limits of Loki, which would otherwise reject every push.
+func (c *Config) validateLabels() error {
+	if c.MaxLabelValueLength < 0 || c.MaxLabels < 0 {
+		return fmt.Errorf("invalid MaxLabelValueLength %d or MaxLabels %d: must not be negative", c.MaxLabelValueLength, c.MaxLabels)
+	}
+	if len(c.Name) > c.MaxLabelValueLength {
+		return fmt.Errorf("invalid Name %q: longer than MaxLabelValueLength %d", c.Name, c.MaxLabelValueLength)
+	}
+
+	static := staticLabels(*c)
+	for _, name := range slices.Sorted(maps.Keys(static)) {
+		if !validLabelName(name) {
+			return fmt.Errorf("invalid label name %q: must match [a-zA-Z_][a-zA-Z0-9_]*", name)
+		}
+		if len(static[name]) > c.MaxLabelValueLength {
+			return fmt.Errorf("invalid label %s: value longer than MaxLabelValueLength %d", name, c.MaxLabelValueLength)
+		}
+	}
+	// The service_name and level labels are added to every stream.
+	if n := len(static) + 2; n > c.MaxLabels {
+		return fmt.Errorf("too many labels: %d static labels including service_name and level exceed MaxLabels %d", n, c.MaxLabels)
+	}
+
+	return nil
+}
+
+// sanitizeLabels makes the extra labels of the entry acceptable to Loki: invalid characters of the names are
+// replaced with underscores and values are made valid UTF-8 and truncated to MaxLabelValueLength.
+func sanitizeLabels(cfg *Config, e Entry) Entry {
+	clean := true
+	for k, v := range e.Labels {
+		if !validLabelName(k) || len(v) > cfg.MaxLabelValueLength || !utf8.ValidString(v) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return e
+	}
+
+	labels := make(map[string]string, len(e.Labels))
+	for k, v := range e.Labels {
+		if k == "" {
+			continue
+		}
+		labels[sanitizeLabelName(k)] = truncateLine(strings.ToValidUTF8(v, "�"), cfg.MaxLabelValueLength)
+	}
+	e.Labels = labels
+
+	return e
+}
+
+// capLabels removes the extra labels of the entry beyond MaxLabels from the stream labels, keeping the
+// static ones and the extra labels first by name, and reports whether labels were removed.
+func capLabels(cfg *Config, labels, static, extra map[string]string) bool {
+	if len(labels) <= cfg.MaxLabels {
+		return false
+	}
+
+	names := slices.Sorted(maps.Keys(extra))
+	for i := len(names) - 1; i >= 0 && len(labels) > cfg.MaxLabels; i-- {
+		k := names[i]
+		if _, ok := static[k]; ok || k == "service_name" || k == "level" {
+			continue
+		}
+		delete(labels, k)
+	}
+
+	return true
+}
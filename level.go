@@ -0,0 +1,147 @@
+package lokilogger
+
+import (
+	"cmp"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// levels lists the supported levels with their severity, which matches the numeric slog levels.
+var levels = map[string]int{
+	"trace":    -8,
+	"debug":    int(slog.LevelDebug),
+	"info":     int(slog.LevelInfo),
+	"warn":     int(slog.LevelWarn),
+	"error":    int(slog.LevelError),
+	"critical": 12,
+	"fatal":    16,
+	"panic":    20,
+}
+
+// levelNames lists the supported levels in errors.
+const levelNames = "trace, debug, info, warn, error, critical, fatal or panic"
+
+// levelAliases are the alternative names of the supported levels.
+var levelAliases = map[string]string{
+	"warning":   "warn",
+	"err":       "error",
+	"crit":      "critical",
+	"emergency": "fatal",
+}
+
+// levelEnabled reports whether entries of level pass the minimum level. Unknown levels are treated as info.
+func levelEnabled(min, level string) bool {
+	if min == "" {
+		return true
+	}
+
+	rank, ok := levels[level]
+	if !ok {
+		rank = levels["info"]
+	}
+
+	return rank >= levels[min]
+}
+
+// SlogLevel returns the level name of a slog level, e.g. "critical" for slog.LevelError+4. Levels between
+// the supported ones are rounded down, e.g. slog.LevelInfo+2 is "info".
+func SlogLevel(level slog.Level) string {
+	name, best := "trace", levels["trace"]
+	for n, rank := range levels {
+		if rank <= int(level) && rank > best {
+			name, best = n, rank
+		}
+	}
+	return name
+}
+
+// normalizeLevel returns the level label value of an entry: the level in lower case with aliases
+// and slog levels such as "ERROR+4" or "12" resolved, mapped by Config.LevelLabels. Unknown
+// levels are kept as they are.
+func normalizeLevel(cfg *Config, level string) string {
+	level = strings.ToLower(level)
+	if alias, ok := levelAliases[level]; ok {
+		level = alias
+	}
+
+	if _, ok := levels[level]; !ok {
+		if rank, ok := slogRank(level); ok {
+			level = SlogLevel(slog.Level(rank))
+		}
+	}
+
+	if mapped, ok := cfg.LevelLabels[level]; ok {
+		return mapped
+	}
+	return level
+}
+
+// slogRank parses a numeric slog level or the name of a slog level with an offset, e.g. "error+4".
+func slogRank(level string) (int, bool) {
+	if n, err := strconv.Atoi(level); err == nil {
+		return n, true
+	}
+
+	i := strings.IndexAny(level, "+-")
+	if i <= 0 {
+		return 0, false
+	}
+	base, ok := levels[level[:i]]
+	if !ok {
+		return 0, false
+	}
+	offset, err := strconv.Atoi(level[i:])
+	if err != nil {
+		return 0, false
+	}
+	return base + offset, true
+}
+
+// defaultLevelTokens are the level tokens recognized at the start of written lines, e.g. "ERROR failed"
+// or "[WARN] slow query". Config.LevelTokens adds to and overrides them.
+var defaultLevelTokens = map[string]string{
+	"TRACE":    "trace",
+	"DEBUG":    "debug",
+	"INFO":     "info",
+	"WARN":     "warn",
+	"WARNING":  "warn",
+	"ERROR":    "error",
+	"CRITICAL": "critical",
+	"CRIT":     "critical",
+	"FATAL":    "fatal",
+	"PANIC":    "panic",
+}
+
+// LevelPattern sets the level of the written lines matching the regexp, e.g. `level=(error|err)\b`.
+type LevelPattern struct {
+	Regexp *regexp.Regexp
+	Level  string
+}
+
+// detectLevel returns the level of a written line and the line without its level token. The first word
+// of the line is looked up in the level tokens, optionally wrapped in brackets or followed by a colon;
+// otherwise the first matching level pattern applies, and the default level without one.
+func detectLevel(cfg *Config, line string) (level, rest string) {
+	word, after, _ := strings.Cut(line, " ")
+	token := strings.TrimSuffix(word, ":")
+	if len(token) > 2 && token[0] == '[' && token[len(token)-1] == ']' {
+		token = token[1 : len(token)-1]
+	}
+
+	if level, ok := cfg.LevelTokens[token]; ok {
+		return level, strings.TrimLeft(after, " ")
+	}
+	if level, ok := defaultLevelTokens[token]; ok {
+		return level, strings.TrimLeft(after, " ")
+	}
+
+	for _, p := range cfg.LevelPatterns {
+		if p.Regexp.MatchString(line) {
+			return p.Level, line
+		}
+	}
+
+	return cmp.Or(cfg.DefaultLevel, "info"), line
+}
@@ -0,0 +1,156 @@
+package lokilogger
+
+import (
+	"cmp"
+	"strings"
+	"time"
+)
+
+// LineFormat selects how written lines are parsed.
+type LineFormat string
+
+const (
+	// LineFormatText parses the timestamp, caller and level prefixes of the standard logger.
+	LineFormatText LineFormat = ""
+	// LineFormatLogfmt parses logfmt lines such as `ts=... level=warn msg="slow query" took=3s`:
+	// the time, level and message are taken from the ts, level and msg keys (or time, lvl, message),
+	// source is attached as caller, and the other pairs become structured metadata. Other lines are
+	// parsed as text.
+	LineFormatLogfmt LineFormat = "logfmt"
+)
+
+// logfmtPair is a key and value of a logfmt line.
+type logfmtPair struct {
+	key, value string
+}
+
+// parseLogfmt splits a logfmt line into its pairs. It reports false if the line isn't logfmt,
+// i.e. it doesn't start with a key=value pair.
+func parseLogfmt(line string) ([]logfmtPair, bool) {
+	var pairs []logfmtPair
+
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] > ' ' && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" || len(pairs) == 0 && (i == len(line) || line[i] != '=') {
+			return nil, false
+		}
+
+		// A key without a value is a flag.
+		if i == len(line) || line[i] != '=' {
+			pairs = append(pairs, logfmtPair{key: key})
+			continue
+		}
+		i++
+
+		if i < len(line) && line[i] == '"' {
+			value, n, ok := unquoteLogfmt(line[i:])
+			if !ok {
+				return nil, false
+			}
+			pairs = append(pairs, logfmtPair{key: key, value: value})
+			i += n
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		pairs = append(pairs, logfmtPair{key: key, value: line[start:i]})
+	}
+
+	return pairs, len(pairs) > 0
+}
+
+// unquoteLogfmt returns the quoted value at the start of s and the number of bytes it spans.
+func unquoteLogfmt(s string) (string, int, bool) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), i + 1, true
+		case '\\':
+			if i++; i == len(s) {
+				return "", 0, false
+			}
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, false
+}
+
+// logfmtEntry converts a logfmt line into an entry. It reports false if the line isn't logfmt.
+func logfmtEntry(cfg *Config, line string) (Entry, bool) {
+	pairs, ok := parseLogfmt(line)
+	if !ok {
+		return Entry{}, false
+	}
+
+	e := Entry{Time: time.Now(), Level: cmp.Or(cfg.DefaultLevel, "info"), Line: line}
+	for _, p := range pairs {
+		switch p.key {
+		case "level", "lvl":
+			e.Level = p.value
+		case "msg", "message":
+			e.Line = p.value
+		case "source", "caller":
+			// The source location of slog.TextHandler, stored like the caller of the standard logger.
+			if e.Metadata == nil {
+				e.Metadata = make(map[string]string, len(pairs))
+			}
+			e.Metadata[callerKey] = p.value
+		case "ts", "time":
+			if t, ok := parseLogfmtTime(cfg, p.value); ok {
+				e.Time = t
+				break
+			}
+			fallthrough
+		default:
+			if e.Metadata == nil {
+				e.Metadata = make(map[string]string, len(pairs))
+			}
+			e.Metadata[sanitizeLabelName(p.key)] = p.value
+		}
+	}
+
+	return e, true
+}
+
+// parseLogfmtTime parses the ts value of a logfmt line as RFC 3339 or with the configured layouts.
+func parseLogfmtTime(cfg *Config, value string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, true
+	}
+
+	loc := cfg.TimeLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range cfg.TimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,14 @@
+module github.com/LynxXIII/loki_logger/lokigorm
+
+go 1.24.0
+
+require (
+	github.com/LynxXIII/loki_logger v0.0.0-20261015082721-24f0ba166dbc
+	gorm.io/gorm v1.31.2
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	golang.org/x/text v0.20.0 // indirect
+)
@@ -0,0 +1,101 @@
+// Package lokigorm provides a GORM logger shipping failed and slow queries with lokilogger. It is a module
+// of its own, so that lokilogger does not depend on GORM.
+package lokigorm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	lokilogger "github.com/LynxXIII/loki_logger"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Logger implements the gorm.io logger.Interface, shipping failed queries
+// at error level and queries slower than SlowThreshold at warn level with the
+// query, duration and affected rows as structured metadata in the stream
+// labeled log_type=sql, like lokilogger.LokiLogger.WrapDB.
+//
+//	db, err := gorm.Open(dialector, &gorm.Config{Logger: lokigorm.New(l, 200*time.Millisecond)})
+type Logger struct {
+	c *lokilogger.Child
+
+	SlowThreshold             time.Duration
+	LogLevel                  gormlogger.LogLevel
+	IgnoreRecordNotFoundError bool
+}
+
+// New returns a GORM logger reporting queries slower than slow. Record not found errors are ignored.
+func New(l *lokilogger.LokiLogger, slow time.Duration) *Logger {
+	c := l.With(map[string]string{"log_type": "sql"})
+	return &Logger{c: c, SlowThreshold: slow, LogLevel: gormlogger.Warn, IgnoreRecordNotFoundError: true}
+}
+
+// LogMode implements logger.Interface.
+func (g *Logger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	c := *g
+	c.LogLevel = level
+	return &c
+}
+
+// Info implements logger.Interface.
+func (g *Logger) Info(ctx context.Context, msg string, args ...any) {
+	if g.LogLevel >= gormlogger.Info {
+		g.c.LogCtx(ctx, "info", fmt.Sprintf(msg, args...))
+	}
+}
+
+// Warn implements logger.Interface.
+func (g *Logger) Warn(ctx context.Context, msg string, args ...any) {
+	if g.LogLevel >= gormlogger.Warn {
+		g.c.LogCtx(ctx, "warn", fmt.Sprintf(msg, args...))
+	}
+}
+
+// Error implements logger.Interface.
+func (g *Logger) Error(ctx context.Context, msg string, args ...any) {
+	if g.LogLevel >= gormlogger.Error {
+		g.c.LogCtx(ctx, "error", fmt.Sprintf(msg, args...))
+	}
+}
+
+// Trace implements logger.Interface. Only failed and slow queries are logged.
+func (g *Logger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if g.LogLevel <= gormlogger.Silent {
+		return
+	}
+	// As for WrapDB, no rows is not a failure.
+	if errors.Is(err, sql.ErrNoRows) || g.IgnoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+
+	slow := g.SlowThreshold
+	if g.LogLevel < gormlogger.Warn {
+		slow = 0
+	}
+	duration := time.Since(begin)
+	if err == nil && (slow <= 0 || duration < slow) {
+		return
+	}
+
+	level, msg := "warn", "slow query: "+duration.String()
+	if err != nil {
+		level, msg = "error", "query failed: "+err.Error()
+	}
+
+	query, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", query),
+		slog.String("duration", duration.String()),
+	}
+	if rows >= 0 {
+		attrs = append(attrs, slog.String("rows", strconv.FormatInt(rows, 10)))
+	}
+
+	g.c.LogCtx(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,75 @@
+// Package lokigrpc provides gRPC server interceptors logging every RPC with lokilogger. It is a module
+// of its own, so that lokilogger does not depend on gRPC.
+package lokigrpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	lokilogger "github.com/LynxXIII/loki_logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns a gRPC interceptor logging every unary RPC
+// with the method, status code and duration as structured metadata in the
+// stream labeled log_type=grpc. Context extractors and trace IDs are attached
+// as for LogCtx.
+//
+//	grpc.NewServer(grpc.ChainUnaryInterceptor(lokigrpc.UnaryServerInterceptor(l)))
+func UnaryServerInterceptor(l *lokilogger.LokiLogger) grpc.UnaryServerInterceptor {
+	c := l.With(map[string]string{"log_type": "grpc"})
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logRPC(ctx, c, info.FullMethod, "unary", start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a gRPC interceptor logging every streaming
+// RPC like UnaryServerInterceptor.
+func StreamServerInterceptor(l *lokilogger.LokiLogger) grpc.StreamServerInterceptor {
+	c := l.With(map[string]string{"log_type": "grpc"})
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logRPC(ss.Context(), c, info.FullMethod, "stream", start, err)
+		return err
+	}
+}
+
+// logRPC logs a finished RPC at error level for server-side failures, warn
+// level for other errors and info level otherwise.
+func logRPC(ctx context.Context, c *lokilogger.Child, method, kind string, start time.Time, err error) {
+	duration := time.Since(start)
+	code := status.Code(err)
+
+	level := "info"
+	switch code {
+	case codes.OK:
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented, codes.DeadlineExceeded:
+		level = "error"
+	default:
+		level = "warn"
+	}
+
+	attrs := []slog.Attr{
+		slog.String("grpc_method", method),
+		slog.String("grpc_type", kind),
+		slog.String("grpc_code", code.String()),
+		slog.String("duration", duration.String()),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		attrs = append(attrs, slog.String("remote_addr", p.Addr.String()))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+
+	c.LogCtx(ctx, level, fmt.Sprintf("%s %s %s", method, code, duration), attrs...)
+}
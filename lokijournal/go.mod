@@ -0,0 +1,7 @@
+module github.com/LynxXIII/loki_logger/lokijournal
+
+go 1.24.0
+
+require github.com/LynxXIII/loki_logger v0.0.0-20261015083006-f686dd014dba
+
+require github.com/coreos/go-systemd/v22 v22.7.0
@@ -0,0 +1,146 @@
+// Package lokijournal ships the entries of the systemd journal with lokilogger. It is a module of its own,
+// so that lokilogger does not depend on go-systemd.
+package lokijournal
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	lokilogger "github.com/LynxXIII/loki_logger"
+	"github.com/coreos/go-systemd/v22/sdjournal"
+)
+
+// journalLevels maps the syslog priorities of journal entries to levels.
+var journalLevels = [...]string{"panic", "fatal", "critical", "error", "warn", "info", "info", "debug"}
+
+// Config selects the journal entries shipped by Tail.
+type Config struct {
+	// Matches filter the entries, e.g. "_SYSTEMD_UNIT=nginx.service". Entries matching any of them are shipped,
+	// all entries when empty.
+	Matches []string
+	// Path is a journal directory to read instead of the system journal, e.g. /var/log/journal.
+	Path string
+	// CursorFile stores the position of the last shipped entry, so that a restart resumes after it instead of
+	// at the end of the journal.
+	CursorFile string
+}
+
+// Tail ships the entries appended to the systemd journal with the logger until ctx is done, making it a small
+// replacement of promtail for single-binary deployments. The unit of an entry is attached as the unit label,
+// its priority sets the level and the syslog identifier, PID and hostname are attached as structured metadata.
+//
+//	go lokijournal.Tail(ctx, l, lokijournal.Config{Matches: []string{"_SYSTEMD_UNIT=nginx.service"}})
+func Tail(ctx context.Context, l *lokilogger.LokiLogger, cfg Config) error {
+	var j *sdjournal.Journal
+	var err error
+	if cfg.Path != "" {
+		j, err = sdjournal.NewJournalFromDir(cfg.Path)
+	} else {
+		j, err = sdjournal.NewJournal()
+	}
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	for i, m := range cfg.Matches {
+		if i > 0 {
+			if err := j.AddDisjunction(); err != nil {
+				return err
+			}
+		}
+		if err := j.AddMatch(m); err != nil {
+			return err
+		}
+	}
+
+	if err := seekJournal(j, cfg.CursorFile); err != nil {
+		return err
+	}
+
+	for {
+		n, err := j.Next()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			j.Wait(time.Second)
+			continue
+		}
+
+		je, err := j.GetEntry()
+		if err != nil {
+			return err
+		}
+		if err := l.Log(journalEntry(je)); err != nil {
+			return err
+		}
+
+		if cfg.CursorFile != "" {
+			if err := os.WriteFile(cfg.CursorFile, []byte(je.Cursor), 0o644); err != nil {
+				l.With(map[string]string{"component": "loki_logger"}).LogCtx(ctx, "warn", "Failed to store the journal cursor", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// seekJournal positions the journal after the cursor stored in the file, or at its end.
+func seekJournal(j *sdjournal.Journal, cursorFile string) error {
+	if cursorFile != "" {
+		cursor, err := os.ReadFile(cursorFile)
+		if err == nil && len(cursor) > 0 {
+			if err := j.SeekCursor(strings.TrimSpace(string(cursor))); err != nil {
+				return err
+			}
+			// The entry at the cursor was shipped already.
+			_, err := j.Next()
+			return err
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	if err := j.SeekTail(); err != nil {
+		return err
+	}
+	_, err := j.Previous()
+	return err
+}
+
+// journalEntry converts the journal entry into an entry.
+func journalEntry(je *sdjournal.JournalEntry) lokilogger.Entry {
+	e := lokilogger.Entry{
+		Time:     time.UnixMicro(int64(je.RealtimeTimestamp)),
+		Level:    "info",
+		Line:     je.Fields[sdjournal.SD_JOURNAL_FIELD_MESSAGE],
+		Metadata: make(map[string]string, 3),
+	}
+
+	if p := je.Fields[sdjournal.SD_JOURNAL_FIELD_PRIORITY]; len(p) == 1 && p[0] >= '0' && p[0] <= '7' {
+		e.Level = journalLevels[p[0]-'0']
+	}
+	if unit := je.Fields[sdjournal.SD_JOURNAL_FIELD_SYSTEMD_UNIT]; unit != "" {
+		e.Labels = map[string]string{"unit": unit}
+	}
+	for key, field := range map[string]string{
+		"syslog_identifier": sdjournal.SD_JOURNAL_FIELD_SYSLOG_IDENTIFIER,
+		"pid":               sdjournal.SD_JOURNAL_FIELD_PID,
+		"hostname":          sdjournal.SD_JOURNAL_FIELD_HOSTNAME,
+	} {
+		if v := je.Fields[field]; v != "" {
+			e.Metadata[key] = v
+		}
+	}
+
+	return e
+}
@@ -0,0 +1,37 @@
+// Package lokikafka provides a lokilogger.KafkaProducer for franz-go clients. It is a module of its own, so
+// that lokilogger does not depend on franz-go.
+package lokikafka
+
+import (
+	"context"
+
+	lokilogger "github.com/LynxXIII/loki_logger"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// franzProducer produces the messages of a KafkaSink with a franz-go client.
+type franzProducer struct {
+	client *kgo.Client
+}
+
+// NewFranzProducer returns a KafkaProducer for KafkaSink using the franz-go client.
+//
+//	client, _ := kgo.NewClient(kgo.SeedBrokers("kafka:9092"))
+//	cfg.Sink = &lokilogger.KafkaSink{Producer: lokikafka.NewFranzProducer(client), Topic: "logs"}
+func NewFranzProducer(client *kgo.Client) lokilogger.KafkaProducer {
+	return &franzProducer{client: client}
+}
+
+// Produce implements KafkaProducer.
+func (p *franzProducer) Produce(ctx context.Context, msgs []lokilogger.KafkaMessage) error {
+	records := make([]*kgo.Record, len(msgs))
+	for i, m := range msgs {
+		r := &kgo.Record{Topic: m.Topic, Key: m.Key, Value: m.Value}
+		for k, v := range m.Headers {
+			r.Headers = append(r.Headers, kgo.RecordHeader{Key: k, Value: []byte(v)})
+		}
+		records[i] = r
+	}
+
+	return p.client.ProduceSync(ctx, records...).FirstErr()
+}
@@ -0,0 +1,12 @@
+module github.com/LynxXIII/loki_logger/lokikafka
+
+go 1.26.0
+
+require github.com/LynxXIII/loki_logger v0.0.0-20261015082731-586a2186916c
+
+require (
+	github.com/klauspost/compress v1.20.0 // indirect
+	github.com/pierrec/lz4/v4 v4.1.30 // indirect
+	github.com/twmb/franz-go v1.22.1
+	github.com/twmb/franz-go/pkg/kmsg v1.14.0 // indirect
+)
@@ -0,0 +1,150 @@
+// Package lokiloggertest provides helpers for testing code logging with lokilogger.
+package lokiloggertest
+
+import (
+	"sync"
+	"time"
+
+	lokilogger "github.com/LynxXIII/loki_logger"
+)
+
+// FakeClock is a lokilogger.Clock that only moves when Advance is called, so
+// flushes and retries can be tested without sleeping:
+//
+//	clock := lokiloggertest.NewFakeClock(time.Now())
+//	l, _ := lokilogger.New(url, lokilogger.WithClock(clock), lokilogger.WithFlushInterval(time.Second))
+//	log.New(l, "", 0).Print("hello")
+//	clock.Advance(time.Second) // Fires the flush timer.
+type FakeClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock returns a fake clock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer firing once the clock is advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) lokilogger.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	t.reset(d)
+	return t
+}
+
+// Advance moves the clock forward by d, firing the timers due in the meantime in order.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		var next *fakeTimer
+		for _, t := range c.timers {
+			if t.active && !t.when.After(end) && (next == nil || t.when.Before(next.when)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		c.now = next.when
+		next.fire()
+	}
+	c.now = end
+}
+
+// BlockUntil waits until n timers are pending, e.g. until the logger waits before a retry.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.pending() < n {
+		c.cond.Wait()
+	}
+}
+
+// pending returns the number of active timers. It must be called with mu held.
+func (c *FakeClock) pending() int {
+	n := 0
+	for _, t := range c.timers {
+		if t.active {
+			n++
+		}
+	}
+	return n
+}
+
+// fakeTimer is a timer of a FakeClock. Like time.Timer since Go 1.23, Stop and
+// Reset discard a fired but unreceived value.
+type fakeTimer struct {
+	clock  *FakeClock
+	ch     chan time.Time
+	when   time.Time
+	active bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := t.active
+	t.active = false
+	t.drain()
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := t.active
+	t.reset(d)
+	return active
+}
+
+// reset schedules the timer d from now. It must be called with the clock's mu held.
+func (t *fakeTimer) reset(d time.Duration) {
+	t.drain()
+	t.when, t.active = t.clock.now.Add(d), true
+	if d <= 0 {
+		t.fire()
+	}
+	t.clock.cond.Broadcast()
+}
+
+// fire delivers the current time. It must be called with the clock's mu held.
+func (t *fakeTimer) fire() {
+	t.active = false
+	select {
+	case t.ch <- t.clock.now:
+	default:
+	}
+}
+
+func (t *fakeTimer) drain() {
+	select {
+	case <-t.ch:
+	default:
+	}
+}
@@ -0,0 +1,117 @@
+package lokiloggertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	lokilogger "github.com/LynxXIII/loki_logger"
+)
+
+// Server is a fake Loki HTTP server recording the JSON payloads pushed to
+// /loki/api/v1/push. The recorded streams are available through the embedded
+// RecordingSink. /ready reports ready.
+//
+//	srv := lokiloggertest.NewServer()
+//	defer srv.Close()
+//	l, _ := lokilogger.New(srv.PushURL())
+type Server struct {
+	*httptest.Server
+	*RecordingSink
+}
+
+// NewServer starts a fake Loki server. Close it when done.
+func NewServer() *Server {
+	s := &Server{RecordingSink: &RecordingSink{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /loki/api/v1/push", s.push)
+	mux.HandleFunc("GET /ready", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ready")
+	})
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// PushURL returns the URL of the push endpoint to configure the logger with.
+func (s *Server) PushURL() string {
+	return s.URL + "/loki/api/v1/push"
+}
+
+func (s *Server) push(w http.ResponseWriter, r *http.Request) {
+	streams, err := decodePush(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.RecordingSink.Push(r.Context(), streams); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodePush decodes the streams of a Loki JSON push request.
+func decodePush(r *http.Request) ([]lokilogger.Stream, error) {
+	var payload struct {
+		Streams []struct {
+			Stream map[string]string   `json:"stream"`
+			Values [][]json.RawMessage `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("decode push: %w", err)
+	}
+
+	streams := make([]lokilogger.Stream, 0, len(payload.Streams))
+	for _, s := range payload.Streams {
+		st := lokilogger.Stream{Labels: s.Stream}
+		for _, v := range s.Values {
+			e, err := decodeValue(v)
+			if err != nil {
+				return nil, err
+			}
+			e.Level = s.Stream["level"]
+			st.Entries = append(st.Entries, e)
+		}
+		streams = append(streams, st)
+	}
+
+	return streams, nil
+}
+
+// decodeValue decodes a ["<unix nanoseconds>", "line", {metadata}] value.
+func decodeValue(v []json.RawMessage) (lokilogger.Entry, error) {
+	var e lokilogger.Entry
+	if len(v) < 2 {
+		return e, fmt.Errorf("decode push: value with %d elements", len(v))
+	}
+
+	var ts string
+	if err := json.Unmarshal(v[0], &ts); err != nil {
+		return e, fmt.Errorf("decode push timestamp: %w", err)
+	}
+	ns, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return e, fmt.Errorf("decode push timestamp: %w", err)
+	}
+	e.Time = time.Unix(0, ns)
+
+	if err := json.Unmarshal(v[1], &e.Line); err != nil {
+		return e, fmt.Errorf("decode push line: %w", err)
+	}
+
+	if len(v) > 2 {
+		if err := json.Unmarshal(v[2], &e.Metadata); err != nil {
+			return e, fmt.Errorf("decode push metadata: %w", err)
+		}
+	}
+
+	return e, nil
+}
@@ -0,0 +1,132 @@
+package lokiloggertest
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	lokilogger "github.com/LynxXIII/loki_logger"
+)
+
+// RecordingSink is a lokilogger.Sink keeping the pushed streams in memory for
+// assertions:
+//
+//	sink := &lokiloggertest.RecordingSink{}
+//	l, _ := lokilogger.New("", lokilogger.WithSink(sink))
+//	log.New(l, "", 0).Print("ERROR payment failed")
+//	l.Flush()
+//	sink.WaitEntries(1, time.Second)
+//	sink.ContainsEntry("payment failed") // true
+type RecordingSink struct {
+	mu      sync.Mutex
+	streams []lokilogger.Stream
+	err     error
+}
+
+// Push records the streams with their labels and tenant. It returns the error set by FailWith.
+func (s *RecordingSink) Push(_ context.Context, streams []lokilogger.Stream) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	for _, st := range streams {
+		s.streams = append(s.streams, lokilogger.Stream{
+			Labels:  maps.Clone(st.Labels),
+			Entries: append([]lokilogger.Entry(nil), st.Entries...),
+			Tenant:  st.Tenant,
+		})
+	}
+	return nil
+}
+
+// FailWith makes the following pushes fail with err, e.g. to test retries. A nil error accepts pushes again.
+func (s *RecordingSink) FailWith(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Streams returns the recorded streams in the order they were pushed. A stream is recorded once per push.
+func (s *RecordingSink) Streams() []lokilogger.Stream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]lokilogger.Stream(nil), s.streams...)
+}
+
+// Entries returns the recorded entries in the order they were pushed.
+func (s *RecordingSink) Entries() []lokilogger.Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []lokilogger.Entry
+	for _, st := range s.streams {
+		entries = append(entries, st.Entries...)
+	}
+	return entries
+}
+
+// Reset discards the recorded streams.
+func (s *RecordingSink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams = nil
+}
+
+// ContainsEntry reports whether a recorded line contains substr.
+func (s *RecordingSink) ContainsEntry(substr string) bool {
+	_, ok := s.find(substr)
+	return ok
+}
+
+// CountByLevel returns the number of recorded entries per level label.
+func (s *RecordingSink) CountByLevel() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, st := range s.streams {
+		counts[st.Labels["level"]] += len(st.Entries)
+	}
+	return counts
+}
+
+// LabelsFor returns the stream labels of the first recorded entry whose line contains substr, or nil.
+func (s *RecordingSink) LabelsFor(substr string) map[string]string {
+	labels, _ := s.find(substr)
+	return labels
+}
+
+// WaitEntries waits until at least n entries were recorded and reports whether they arrived within the timeout.
+// Batches are sent in the background, so wait before asserting after a Flush.
+func (s *RecordingSink) WaitEntries(n int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if len(s.Entries()) >= n {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// find returns the labels of the first stream with an entry whose line contains substr.
+func (s *RecordingSink) find(substr string) (map[string]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, st := range s.streams {
+		for _, e := range st.Entries {
+			if strings.Contains(e.Line, substr) {
+				return maps.Clone(st.Labels), true
+			}
+		}
+	}
+	return nil, false
+}
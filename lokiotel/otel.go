@@ -0,0 +1,20 @@
+// Package lokiotel provides a lokilogger.TraceExtractor for OpenTelemetry. It is a module of its own, so
+// that lokilogger does not depend on OpenTelemetry.
+package lokiotel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceExtractor is a lokilogger.TraceExtractor returning the IDs of the active OpenTelemetry span.
+//
+//	cfg.TraceExtractor = lokiotel.TraceExtractor
+func TraceExtractor(ctx context.Context) (traceID, spanID string) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", ""
+	}
+	return sc.TraceID().String(), sc.SpanID().String()
+}
@@ -0,0 +1,92 @@
+package lokilogger
+
+import (
+	"maps"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultMultilineTimeout = 500 * time.Millisecond
+
+// GoStackTracePattern matches the continuation lines of Go panics and stack
+// traces: blank and indented lines, goroutine headers, function calls and
+// "created by" lines. Use it as Config.MultilinePattern.
+var GoStackTracePattern = regexp.MustCompile(`^(\s*$|\s|goroutine \d+ \[|created by |\[signal |[\w./*()\[\]-]+\(.*\)$)`)
+
+// multiline joins continuation lines written separately, e.g. stack traces,
+// with the preceding line into a single entry. The pending entry is shipped
+// when a line not matching Config.MultilinePattern arrives or after
+// Config.MultilineTimeout without further lines.
+type multiline struct {
+	mu      sync.Mutex
+	pending []string
+	labels  map[string]string // Extra stream labels of the pending entry.
+	timer   *time.Timer
+}
+
+// collect passes the line to the multiline collector if configured, otherwise ships it right away.
+func (l *LokiLogger) collect(line string, labels map[string]string) {
+	cfg := l.config()
+	if cfg.MultilinePattern == nil {
+		e := parseEntry(cfg, line)
+		e.Labels = labels
+		l.ship(e)
+		return
+	}
+
+	line = strings.TrimRight(line, "\n")
+	m := &l.multiline
+
+	timeout := cfg.MultilineTimeout
+	if timeout <= 0 {
+		timeout = defaultMultilineTimeout
+	}
+
+	m.mu.Lock()
+	var prev []string
+	prevLabels := m.labels
+	if len(m.pending) > 0 && maps.Equal(m.labels, labels) && cfg.MultilinePattern.MatchString(line) {
+		m.pending = append(m.pending, line)
+	} else {
+		prev, m.pending, m.labels = m.pending, []string{line}, labels
+	}
+
+	// The pending entry is shipped once no further lines arrive within the timeout.
+	if m.timer == nil {
+		m.timer = time.AfterFunc(timeout, l.flushMultiline)
+	} else {
+		m.timer.Reset(timeout)
+	}
+	m.mu.Unlock()
+
+	l.shipMultiline(prev, prevLabels)
+}
+
+// flushMultiline ships the pending entry of the multiline collector.
+func (l *LokiLogger) flushMultiline() {
+	m := &l.multiline
+
+	m.mu.Lock()
+	prev, labels := m.pending, m.labels
+	m.pending, m.labels = nil, nil
+	m.mu.Unlock()
+
+	l.shipMultiline(prev, labels)
+}
+
+// shipMultiline ships the joined lines as a single entry. Panics are logged at error level.
+func (l *LokiLogger) shipMultiline(lines []string, labels map[string]string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	e := parseEntry(l.config(), strings.Join(lines, "\n"))
+	e.Labels = labels
+	if strings.HasPrefix(e.Line, "panic: ") || strings.HasPrefix(e.Line, "fatal error: ") {
+		e.Level = "error"
+	}
+
+	l.ship(e)
+}
@@ -0,0 +1,189 @@
+package lokilogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryWindow refreshes OAuth2 tokens this long before they expire.
+const tokenExpiryWindow = time.Minute
+
+// OAuth2Config authenticates the requests with a token obtained with the OAuth2 client credentials
+// grant, e.g. from Azure AD or any OIDC provider in front of Loki. The certificate of the token endpoint is
+// always verified against the system roots, regardless of Config.TLSConfig.
+type OAuth2Config struct {
+	// TokenURL is the token endpoint, e.g. https://login.microsoftonline.com/<tenant>/oauth2/v2.0/token.
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// Scopes requested for the token, e.g. api://loki/.default for Azure AD.
+	Scopes []string
+	// EndpointParams are additional parameters of the token request, e.g. audience.
+	EndpointParams url.Values
+}
+
+// tokenSource provides the bearer tokens of the requests.
+type tokenSource interface {
+	// token returns the current token.
+	token(ctx context.Context) (string, error)
+	// invalidate discards the token after it was rejected, so that the next call fetches a new one.
+	invalidate(token string)
+}
+
+// bearerTransport adds a bearer token of the source to the requests. A request rejected with
+// 401 Unauthorized is sent once more with a fresh token, as the token may have just been rotated.
+type bearerTransport struct {
+	next   http.RoundTripper
+	source tokenSource
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	payload, err := requestPayload(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.send(req, payload, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	t.source.invalidate(token)
+	fresh, err := t.source.token(req.Context())
+	if err != nil || fresh == token {
+		// Nothing changed, so the rejection is final.
+		return resp, nil
+	}
+
+	resp.Body.Close()
+	return t.send(req, payload, fresh)
+}
+
+// send sends a copy of the request with the payload and token, as a RoundTripper must not modify the request.
+func (t *bearerTransport) send(req *http.Request, payload []byte, token string) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Body = nil
+	if payload != nil {
+		req.Body = io.NopCloser(bytes.NewReader(payload))
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return t.next.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport.
+func (t *bearerTransport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// oauth2Source fetches tokens with the client credentials grant, shortly before the previous one expires.
+type oauth2Source struct {
+	cfg    *OAuth2Config
+	client *http.Client
+
+	mu     sync.Mutex
+	cached string
+	expiry time.Time
+	// inParams sends the client credentials as form parameters once the endpoint rejected basic authentication.
+	inParams bool
+}
+
+func (s *oauth2Source) invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached == token {
+		s.cached = ""
+	}
+}
+
+func (s *oauth2Source) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != "" && time.Now().Before(s.expiry) {
+		return s.cached, nil
+	}
+
+	resp, err := s.requestToken(ctx, s.inParams)
+	if err == nil && !s.inParams && (resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized) {
+		// Some providers only accept the client credentials as form parameters.
+		resp.Body.Close()
+		resp, err = s.requestToken(ctx, true)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			s.inParams = true
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("oauth2: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("oauth2: token endpoint returned status code %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return "", fmt.Errorf("oauth2: token endpoint returned status code %d: %s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+
+	s.cached = body.AccessToken
+	// Tokens without an expiry are refreshed after an hour or when Loki rejects them.
+	s.expiry = time.Now().Add(time.Hour)
+	if body.ExpiresIn > 0 {
+		s.expiry = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - tokenExpiryWindow)
+	}
+
+	return s.cached, nil
+}
+
+// requestToken requests a token with the client credentials grant, authenticating with
+// basic authentication or, with inParams, the client_id and client_secret parameters.
+func (s *oauth2Source) requestToken(ctx context.Context, inParams bool) (*http.Response, error) {
+	form := url.Values{}
+	for k, v := range s.cfg.EndpointParams {
+		form[k] = v
+	}
+	form.Set("grant_type", "client_credentials")
+	if len(s.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+	if inParams {
+		form.Set("client_id", s.cfg.ClientID)
+		form.Set("client_secret", s.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if !inParams {
+		req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.cfg.ClientSecret))
+	}
+
+	return s.client.Do(req)
+}
@@ -0,0 +1,27 @@
+package lokilogger
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAuth2VerifiesTokenEndpoint(t *testing.T) {
+	idp := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"secret","expires_in":3600}`))
+	}))
+	defer idp.Close()
+
+	// Without a TLSConfig the Loki certificate is not verified, but the one of the token endpoint is.
+	client := newHTTPClient(Config{URL: "https://loki.invalid", OAuth2: &OAuth2Config{TokenURL: idp.URL, ClientID: "logger"}})
+	source := client.Transport.(*bearerTransport).source
+
+	_, err := source.token(context.Background())
+	var unknown x509.UnknownAuthorityError
+	if !errors.As(err, &unknown) {
+		t.Errorf("token: %v, want x509.UnknownAuthorityError", err)
+	}
+}
@@ -0,0 +1,95 @@
+package lokilogger
+
+import (
+	"context"
+	"time"
+)
+
+// Option configures a logger created by New.
+type Option func(*options)
+
+type options struct {
+	ctx context.Context
+	cfg Config
+}
+
+// New creates a logger pushing to the Loki url. Unlike Init it doesn't
+// change the standard log package; use the logger as an io.Writer, e.g.
+// log.New(l, "", log.LstdFlags|log.LUTC|log.Lmicroseconds|log.Lshortfile).
+// Unset values default to BatchSize 100, FlushInterval 5s and RetryCount 3.
+func New(url string, opts ...Option) (*LokiLogger, error) {
+	o := options{ctx: context.Background(), cfg: Config{URL: url}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return newLogger(o.ctx, o.cfg)
+}
+
+// WithContext sets the context controlling the logger lifetime. The
+// remaining logs are flushed when it is done.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) { o.ctx = ctx }
+}
+
+// WithName sets the service name used for identification of logs in Loki.
+func WithName(name string) Option {
+	return func(o *options) { o.cfg.Name = name }
+}
+
+// WithAccessToken sets the authentication token for accessing the Loki API.
+func WithAccessToken(token string) Option {
+	return func(o *options) { o.cfg.AccessToken = token }
+}
+
+// WithBatchSize sets the number of logs to batch before sending to Loki.
+func WithBatchSize(n int) Option {
+	return func(o *options) { o.cfg.BatchSize = n }
+}
+
+// WithFlushInterval sets the maximum time logs wait in the batch.
+func WithFlushInterval(d time.Duration) Option {
+	return func(o *options) { o.cfg.FlushInterval = d }
+}
+
+// WithRetryCount sets the number of push attempts per batch.
+func WithRetryCount(n int) Option {
+	return func(o *options) { o.cfg.RetryCount = n }
+}
+
+// WithLabels adds static labels attached to every stream.
+func WithLabels(labels map[string]string) Option {
+	return func(o *options) {
+		if o.cfg.Labels == nil {
+			o.cfg.Labels = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			o.cfg.Labels[k] = v
+		}
+	}
+}
+
+// WithMiddlewares appends middlewares executed for every entry before batching.
+func WithMiddlewares(m ...Middleware) Option {
+	return func(o *options) { o.cfg.Middlewares = append(o.cfg.Middlewares, m...) }
+}
+
+// WithContextExtractors appends extractors attaching context values to the entries logged with LogCtx.
+func WithContextExtractors(x ...ContextExtractor) Option {
+	return func(o *options) { o.cfg.ContextExtractors = append(o.cfg.ContextExtractors, x...) }
+}
+
+// WithSink overrides the destination of the logs.
+func WithSink(s Sink) Option {
+	return func(o *options) { o.cfg.Sink = s }
+}
+
+// WithClock sets the clock driving the flush timer and retry waits, e.g. a fake clock in tests.
+func WithClock(c Clock) Option {
+	return func(o *options) { o.cfg.Clock = c }
+}
+
+// WithConfig applies fn to the configuration, giving access to the settings without a dedicated option.
+func WithConfig(fn func(*Config)) Option {
+	return func(o *options) { fn(&o.cfg) }
+}
@@ -0,0 +1,30 @@
+package lokilogger
+
+import (
+	"sort"
+	"time"
+)
+
+// orderTimestamps sorts the entries of the stream identified by key by time and
+// nudges equal or backward timestamps forward by a nanosecond, also relative to
+// the last entry of the stream sent in a previous batch. Loki rejects entries
+// older than the newest entry of a stream. It must be called with orderMu held.
+func (l *LokiLogger) orderTimestamps(key string, entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+
+	if l.lastTimes == nil {
+		l.lastTimes = make(map[string]time.Time)
+	}
+
+	last := l.lastTimes[key]
+	for i := range entries {
+		if !entries[i].Time.After(last) {
+			entries[i].Time = last.Add(time.Nanosecond)
+		}
+		last = entries[i].Time
+	}
+
+	l.lastTimes[key] = last
+}
@@ -0,0 +1,161 @@
+package lokilogger
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+)
+
+// Protocol selects the wire format used to push logs.
+type Protocol string
+
+const (
+	// ProtocolLoki pushes JSON to the native Loki push API (/loki/api/v1/push).
+	ProtocolLoki Protocol = "loki"
+	// ProtocolOTLP pushes OTLP logs as protobuf over HTTP (/v1/logs), e.g. to an OpenTelemetry Collector.
+	ProtocolOTLP Protocol = "otlp"
+)
+
+// OTLP severity numbers of the supported levels.
+var otlpSeverity = map[string]uint64{
+	"trace":    1,
+	"debug":    5,
+	"info":     9,
+	"warn":     13,
+	"error":    17,
+	"critical": 19,
+	"fatal":    21,
+	"panic":    24,
+}
+
+// protoBuf is a minimal protocol buffers encoder.
+type protoBuf []byte
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func (p *protoBuf) tag(field, wire int) {
+	*p = binary.AppendUvarint(*p, uint64(field<<3|wire))
+}
+
+func (p *protoBuf) varint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	p.tag(field, wireVarint)
+	*p = binary.AppendUvarint(*p, v)
+}
+
+func (p *protoBuf) fixed64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	p.tag(field, wireFixed64)
+	*p = binary.LittleEndian.AppendUint64(*p, v)
+}
+
+func (p *protoBuf) bytes(field int, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	p.tag(field, wireBytes)
+	*p = binary.AppendUvarint(*p, uint64(len(b)))
+	*p = append(*p, b...)
+}
+
+func (p *protoBuf) string(field int, s string) {
+	p.bytes(field, []byte(s))
+}
+
+// message encodes a nested message written by fn.
+func (p *protoBuf) message(field int, fn func(m *protoBuf)) {
+	var m protoBuf
+	fn(&m)
+	p.tag(field, wireBytes)
+	*p = binary.AppendUvarint(*p, uint64(len(m)))
+	*p = append(*p, m...)
+}
+
+// keyValue encodes an opentelemetry.proto.common.v1.KeyValue with a string value.
+func (p *protoBuf) keyValue(field int, key, value string) {
+	p.message(field, func(kv *protoBuf) {
+		kv.string(1, key)
+		kv.message(2, func(v *protoBuf) {
+			v.string(1, value)
+		})
+	})
+}
+
+// otlpAttributeName maps Loki label names to OpenTelemetry semantic convention attribute names.
+func otlpAttributeName(label string) string {
+	if label == "service_name" {
+		return "service.name"
+	}
+	return label
+}
+
+// encodeOTLP encodes the streams as an opentelemetry.proto.collector.logs.v1.ExportLogsServiceRequest.
+// Each stream becomes a resource whose attributes are the stream labels; the
+// level label is mapped to the severity of the log records.
+func encodeOTLP(streams []Stream) []byte {
+	var req protoBuf
+
+	for _, s := range streams {
+		level := s.Labels["level"]
+
+		req.message(1, func(rl *protoBuf) {
+			rl.message(1, func(res *protoBuf) {
+				for _, k := range sortedKeys(s.Labels) {
+					if k != "level" {
+						res.keyValue(1, otlpAttributeName(k), s.Labels[k])
+					}
+				}
+			})
+
+			rl.message(2, func(sl *protoBuf) {
+				sl.message(1, func(scope *protoBuf) {
+					scope.string(1, "github.com/LynxXIII/loki_logger")
+				})
+
+				for _, e := range s.Entries {
+					sl.message(2, func(rec *protoBuf) {
+						ts := uint64(e.Time.UnixNano())
+						rec.fixed64(1, ts)
+						rec.varint(2, otlpSeverity[level])
+						rec.string(3, level)
+						rec.message(5, func(body *protoBuf) {
+							body.string(1, e.Line)
+						})
+						for _, k := range sortedKeys(e.Metadata) {
+							if k != "trace_id" && k != "span_id" {
+								rec.keyValue(6, k, e.Metadata[k])
+							}
+						}
+						if id, err := hex.DecodeString(e.Metadata["trace_id"]); err == nil && len(id) == 16 {
+							rec.bytes(9, id)
+						}
+						if id, err := hex.DecodeString(e.Metadata["span_id"]); err == nil && len(id) == 8 {
+							rec.bytes(10, id)
+						}
+						rec.fixed64(11, ts)
+					})
+				}
+			})
+		})
+	}
+
+	return req
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,188 @@
+package lokilogger
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// Validation errors Loki reports per rejected entry in the body of a 400 response.
+	lineTooLongRe   = regexp.MustCompile(`Max entry size '(\d+)' bytes exceeded for stream '([^']*)'`)
+	tooOldRe        = regexp.MustCompile(`entry for stream '([^']*)' has timestamp too old: ([^,]+), oldest acceptable timestamp is: ([^\s,]+)`)
+	tooNewRe        = regexp.MustCompile(`entry for stream '([^']*)' has timestamp too new: ([^\s,]+)`)
+	ignoredRe       = regexp.MustCompile(`entry with timestamp (.+?) ignored, reason: '[^']*',? for stream: (\{[^}]*\})`)
+	streamLabelRe   = regexp.MustCompile(`([a-zA-Z_][a-zA-Z0-9_]*)="((?:[^"\\]|\\.)*)"`)
+	rejectedTimeFmt = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999 -0700 MST"}
+)
+
+// rejection describes entries of a stream Loki refused to ingest.
+type rejection struct {
+	stream   string           // labelsKey of the stream, empty if it could not be parsed.
+	maxSize  int              // Truncate lines longer than maxSize; zero drops the matching entries.
+	rejected func(Entry) bool // Reports whether the entry was rejected.
+}
+
+// parseRejections extracts the rejected entries from the body of a 400 response.
+func parseRejections(body string) []rejection {
+	var rejs []rejection
+
+	for _, line := range strings.Split(body, "\n") {
+		if m := lineTooLongRe.FindStringSubmatch(line); m != nil {
+			size, _ := strconv.Atoi(m[1])
+			rejs = append(rejs, rejection{
+				stream:   parseStreamKey(m[2]),
+				maxSize:  size,
+				rejected: func(e Entry) bool { return len(e.Line) > size },
+			})
+			continue
+		}
+
+		if m := tooOldRe.FindStringSubmatch(line); m != nil {
+			if oldest, ok := parseRejectedTime(m[3]); ok {
+				rejs = append(rejs, rejection{
+					stream:   parseStreamKey(m[1]),
+					rejected: func(e Entry) bool { return e.Time.Before(oldest) },
+				})
+			}
+			continue
+		}
+
+		if m := tooNewRe.FindStringSubmatch(line); m != nil {
+			if ts, ok := parseRejectedTime(m[2]); ok {
+				rejs = append(rejs, rejection{stream: parseStreamKey(m[1]), rejected: sameTime(ts)})
+			}
+			continue
+		}
+
+		// Out of order and too far behind entries.
+		if m := ignoredRe.FindStringSubmatch(line); m != nil {
+			if ts, ok := parseRejectedTime(m[1]); ok {
+				rejs = append(rejs, rejection{stream: parseStreamKey(m[2]), rejected: sameTime(ts)})
+			}
+		}
+	}
+
+	return rejs
+}
+
+// sameTime matches entries with the timestamp, which Loki may report with second precision only.
+func sameTime(ts time.Time) func(Entry) bool {
+	return func(e Entry) bool {
+		if ts.Nanosecond() == 0 {
+			return e.Time.Truncate(time.Second).Equal(ts)
+		}
+		return e.Time.Equal(ts)
+	}
+}
+
+// parseRejectedTime parses a timestamp reported in a validation error.
+func parseRejectedTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range rejectedTimeFmt {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseStreamKey converts a stream selector such as {app="api", level="info"} into its labelsKey.
+func parseStreamKey(s string) string {
+	labels := make(map[string]string)
+	for _, m := range streamLabelRe.FindAllStringSubmatch(s, -1) {
+		v, err := strconv.Unquote(`"` + m[2] + `"`)
+		if err != nil {
+			v = m[2]
+		}
+		labels[m[1]] = v
+	}
+
+	if len(labels) == 0 {
+		return ""
+	}
+	return labelsKey(labels)
+}
+
+// rejectEntries handles a partial failure: Loki answered 400 naming the entries it
+// rejected while accepting the others. It returns the streams to resend with the
+// rejected entries dropped, or truncated when their lines are too long, and the
+// number of dropped entries. It reports false if err isn't such a partial failure.
+func rejectEntries(streams []Stream, err error) ([]Stream, int, bool) {
+	var pe *PushError
+	if !errors.As(err, &pe) || pe.StatusCode != http.StatusBadRequest {
+		return nil, 0, false
+	}
+
+	rejs := parseRejections(pe.Body)
+	if len(rejs) == 0 {
+		return nil, 0, false
+	}
+
+	dropped, truncated := 0, 0
+	rest := make([]Stream, 0, len(streams))
+
+	for _, s := range streams {
+		key := labelsKey(s.Labels)
+		entries := make([]Entry, 0, len(s.Entries))
+
+	entries:
+		for _, e := range s.Entries {
+			for _, r := range rejs {
+				if r.stream != "" && r.stream != key || !r.rejected(e) {
+					continue
+				}
+				if r.maxSize == 0 {
+					dropped++
+					continue entries
+				}
+				e = truncateEntry(e, r.maxSize)
+				truncated++
+			}
+			entries = append(entries, e)
+		}
+
+		if len(entries) > 0 {
+			// The copy keeps the tenant and route of the stream.
+			st := s
+			st.Entries = entries
+			rest = append(rest, st)
+		}
+	}
+
+	// Resending is pointless unless some of the entries were identified.
+	if dropped == 0 && truncated == 0 {
+		return nil, 0, false
+	}
+
+	return rest, dropped, true
+}
+
+// truncateEntry shortens the line of the entry to at most n bytes and flags it with the "truncated" structured metadata field.
+func truncateEntry(e Entry, n int) Entry {
+	e.Line = truncateLine(e.Line, n)
+
+	md := make(map[string]string, len(e.Metadata)+1)
+	for k, v := range e.Metadata {
+		md[k] = v
+	}
+	md["truncated"] = "true"
+	e.Metadata = md
+
+	return e
+}
+
+// truncateLine shortens the line to at most n bytes without splitting a UTF-8 sequence.
+func truncateLine(line string, n int) string {
+	if len(line) <= n {
+		return line
+	}
+	for n > 0 && !utf8.RuneStart(line[n]) {
+		n--
+	}
+	return line[:n]
+}
@@ -0,0 +1,327 @@
+package lokilogger
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Stage is a step of Config.Pipeline transforming the entries like the pipeline stages of Promtail,
+// for services shipping without an agent. Exactly one field must be set. Stages extract values from
+// the line into a map shared by the following stages, which turn them into labels, structured metadata,
+// the timestamp or a new line. The fields mirror the pipeline_stages of the configuration file:
+//
+//	pipeline_stages:
+//	  - regex:
+//	      expression: '^(?P<method>\S+) (?P<path>\S+) (?P<status>\d+)'
+//	  - labels:
+//	      method:
+//	  - structured_metadata:
+//	      status:
+type Stage struct {
+	Regex    *RegexStage    `json:"regex,omitempty"`
+	JSON     *JSONStage     `json:"json,omitempty"`
+	Template *TemplateStage `json:"template,omitempty"`
+	// Labels sets the stream labels to the extracted values, by label name and extracted name (the
+	// label name when empty). The level label sets the level of the entry.
+	Labels map[string]string `json:"labels,omitempty"`
+	// StructuredMetadata attaches extracted values like Labels as structured metadata.
+	StructuredMetadata map[string]string `json:"structured_metadata,omitempty"`
+	Output             *OutputStage      `json:"output,omitempty"`
+	Timestamp          *TimestampStage   `json:"timestamp,omitempty"`
+	// LabelDrop removes the labels from the stream labels of the entry.
+	LabelDrop []string `json:"labeldrop,omitempty"`
+}
+
+// RegexStage extracts the named groups of the expression matching the line or an extracted value.
+type RegexStage struct {
+	Expression string `json:"expression"`
+	Source     string `json:"source,omitempty"` // Extracted name to match instead of the line.
+}
+
+// JSONStage extracts values of a JSON line or extracted value, by extracted name and dotted path
+// into the object, e.g. {"user": "request.user.id"}. The path is the name when empty.
+type JSONStage struct {
+	Expressions map[string]string `json:"expressions"`
+	Source      string            `json:"source,omitempty"`
+}
+
+// TemplateStage sets an extracted value to the result of a text/template executed with the extracted
+// values and the line as .Entry, e.g. `{{ .method | ToUpper }} {{ .path }}`. The functions ToLower,
+// ToUpper, TrimSpace and Replace are available.
+type TemplateStage struct {
+	Source   string `json:"source"`
+	Template string `json:"template"`
+}
+
+// OutputStage replaces the line with an extracted value.
+type OutputStage struct {
+	Source string `json:"source"`
+}
+
+// TimestampStage sets the time of the entry from an extracted value in the format: a time layout,
+// RFC3339, RFC3339Nano, Unix, UnixMs or UnixNs. Times without a zone are in the Location, UTC by
+// default. Entries keep their time when the value is missing or invalid.
+type TimestampStage struct {
+	Source   string `json:"source"`
+	Format   string `json:"format"`
+	Location string `json:"location,omitempty"` // IANA name, e.g. Europe/Berlin.
+}
+
+// stageFunc applies a compiled stage to the entry and the extracted values.
+type stageFunc func(e *Entry, extracted map[string]string)
+
+// pipeline is the compiled Config.Pipeline.
+type pipeline []stageFunc
+
+var templateFuncs = template.FuncMap{
+	"ToLower":   strings.ToLower,
+	"ToUpper":   strings.ToUpper,
+	"TrimSpace": strings.TrimSpace,
+	"Replace":   strings.ReplaceAll,
+}
+
+// compilePipeline compiles the stages, reporting the first invalid one.
+func compilePipeline(stages []Stage) (pipeline, error) {
+	p := make(pipeline, 0, len(stages))
+	for i, s := range stages {
+		f, err := s.compile()
+		if err != nil {
+			return nil, fmt.Errorf("invalid Pipeline[%d]: %w", i, err)
+		}
+		p = append(p, f)
+	}
+	return p, nil
+}
+
+// compile returns the function applying the stage.
+func (s *Stage) compile() (stageFunc, error) {
+	set := 0
+	for _, ok := range []bool{s.Regex != nil, s.JSON != nil, s.Template != nil, s.Labels != nil, s.StructuredMetadata != nil, s.Output != nil, s.Timestamp != nil, s.LabelDrop != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("exactly one stage must be set, got %d", set)
+	}
+
+	switch {
+	case s.Regex != nil:
+		re, err := regexp.Compile(s.Regex.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("regex: %w", err)
+		}
+		source := s.Regex.Source
+		return func(e *Entry, extracted map[string]string) {
+			m := re.FindStringSubmatch(sourceValue(e, extracted, source))
+			for i, name := range re.SubexpNames() {
+				if name != "" && m != nil {
+					extracted[name] = m[i]
+				}
+			}
+		}, nil
+
+	case s.JSON != nil:
+		expressions, source := s.JSON.Expressions, s.JSON.Source
+		return func(e *Entry, extracted map[string]string) {
+			var obj map[string]any
+			if json.Unmarshal([]byte(sourceValue(e, extracted, source)), &obj) != nil {
+				return
+			}
+			for name, path := range expressions {
+				if v, ok := jsonPath(obj, cmp.Or(path, name)); ok {
+					extracted[name] = v
+				}
+			}
+		}, nil
+
+	case s.Template != nil:
+		tmpl, err := template.New("stage").Funcs(templateFuncs).Option("missingkey=zero").Parse(s.Template.Template)
+		if err != nil {
+			return nil, fmt.Errorf("template: %w", err)
+		}
+		if s.Template.Source == "" {
+			return nil, fmt.Errorf("template: missing source")
+		}
+		source := s.Template.Source
+		return func(e *Entry, extracted map[string]string) {
+			data := make(map[string]string, len(extracted)+1)
+			maps.Copy(data, extracted)
+			data["Entry"] = e.Line
+
+			var b strings.Builder
+			if tmpl.Execute(&b, data) == nil {
+				extracted[source] = b.String()
+			}
+		}, nil
+
+	case s.Labels != nil:
+		names := s.Labels
+		return func(e *Entry, extracted map[string]string) {
+			labels := maps.Clone(e.Labels)
+			for name, key := range names {
+				v, ok := extracted[cmp.Or(key, name)]
+				if !ok {
+					continue
+				}
+				if name == "level" {
+					e.Level = v
+					continue
+				}
+				if labels == nil {
+					labels = make(map[string]string, len(names))
+				}
+				labels[sanitizeLabelName(name)] = v
+			}
+			e.Labels = labels
+		}, nil
+
+	case s.StructuredMetadata != nil:
+		names := s.StructuredMetadata
+		return func(e *Entry, extracted map[string]string) {
+			md := maps.Clone(e.Metadata)
+			for name, key := range names {
+				if v, ok := extracted[cmp.Or(key, name)]; ok {
+					if md == nil {
+						md = make(map[string]string, len(names))
+					}
+					md[sanitizeLabelName(name)] = v
+				}
+			}
+			e.Metadata = md
+		}, nil
+
+	case s.Output != nil:
+		source := s.Output.Source
+		return func(e *Entry, extracted map[string]string) {
+			if v, ok := extracted[source]; ok {
+				e.Line = v
+			}
+		}, nil
+
+	case s.Timestamp != nil:
+		loc := time.UTC
+		if s.Timestamp.Location != "" {
+			var err error
+			if loc, err = time.LoadLocation(s.Timestamp.Location); err != nil {
+				return nil, fmt.Errorf("timestamp: %w", err)
+			}
+		}
+		parse, err := timestampParser(s.Timestamp.Format, loc)
+		if err != nil {
+			return nil, err
+		}
+		source := s.Timestamp.Source
+		return func(e *Entry, extracted map[string]string) {
+			if v, ok := extracted[source]; ok {
+				if t, err := parse(v); err == nil {
+					e.Time = t
+				}
+			}
+		}, nil
+
+	default:
+		drop := s.LabelDrop
+		return func(e *Entry, _ map[string]string) {
+			labels := maps.Clone(e.Labels)
+			for _, name := range drop {
+				delete(labels, name)
+			}
+			e.Labels = labels
+		}, nil
+	}
+}
+
+// apply runs the entry through the stages.
+func (p pipeline) apply(e Entry) Entry {
+	if len(p) == 0 {
+		return e
+	}
+
+	extracted := make(map[string]string)
+	for _, stage := range p {
+		stage(&e, extracted)
+	}
+	return e
+}
+
+// sourceValue returns the extracted value named source, or the line when source is empty.
+func sourceValue(e *Entry, extracted map[string]string, source string) string {
+	if source == "" {
+		return e.Line
+	}
+	return extracted[source]
+}
+
+// jsonPath returns the value at the dotted path of the object, encoding objects and arrays as JSON.
+func jsonPath(obj map[string]any, path string) (string, bool) {
+	var v any = obj
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		if v, ok = m[key]; !ok {
+			return "", false
+		}
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case nil:
+		return "", true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err == nil
+	}
+}
+
+// timestampParser returns the parser of timestamps in the format of a TimestampStage.
+func timestampParser(format string, loc *time.Location) (func(string) (time.Time, error), error) {
+	unix := func(unit time.Duration) func(string) (time.Time, error) {
+		return func(v string) (time.Time, error) {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(0, n*int64(unit)), nil
+		}
+	}
+
+	switch format {
+	case "":
+		return nil, fmt.Errorf("timestamp: missing format")
+	case "Unix":
+		return func(v string) (time.Time, error) {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(0, int64(f*float64(time.Second))), nil
+		}, nil
+	case "UnixMs":
+		return unix(time.Millisecond), nil
+	case "UnixNs":
+		return unix(time.Nanosecond), nil
+	case "RFC3339":
+		format = time.RFC3339
+	case "RFC3339Nano":
+		format = time.RFC3339Nano
+	}
+
+	return func(v string) (time.Time, error) {
+		return time.ParseInLocation(format, v, loc)
+	}, nil
+}
@@ -0,0 +1,156 @@
+package lokilogger
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultMaxQueueSize = 100000
+
+// queue is the ingestion queue between Write and the batching worker. Writers
+// append to a random one of several shards, each with its own mutex, so
+// concurrent writes rarely contend. Entries carry a sequence number assigned
+// under the shard mutex, which keeps every shard ordered and lets drain merge
+// them back into the order of the writes.
+type queue struct {
+	shards []queueShard
+	seq    atomic.Int64 // Number of pushed entries.
+	taken  atomic.Int64 // Number of drained entries.
+	heads  []int        // Scratch merge positions, owned by the drainer.
+}
+
+type queueShard struct {
+	mu      sync.Mutex
+	entries []queuedEntry
+	spare   []queuedEntry // Entries taken by the drainer, swapped with entries.
+	last    atomic.Int64  // Time of the last push in Unix nanoseconds.
+
+	_ [64]byte // Keeps the shards on separate cache lines.
+}
+
+type queuedEntry struct {
+	seq int64
+	Entry
+}
+
+func newQueue() *queue {
+	n := runtime.GOMAXPROCS(0)
+	return &queue{shards: make([]queueShard, n), heads: make([]int, n)}
+}
+
+// push appends the entry written at now and returns the approximate number of queued entries.
+func (q *queue) push(e Entry, now time.Time) int {
+	s := &q.shards[rand.N(len(q.shards))]
+
+	s.mu.Lock()
+	seq := q.seq.Add(1)
+	s.entries = append(s.entries, queuedEntry{seq: seq, Entry: e})
+	s.mu.Unlock()
+
+	s.last.Store(now.UnixNano())
+
+	return int(seq - q.taken.Load())
+}
+
+// len returns the number of queued entries.
+func (q *queue) len() int {
+	taken := q.taken.Load()
+	return int(q.seq.Load() - taken)
+}
+
+// lastPush returns the time of the last push.
+func (q *queue) lastPush() time.Time {
+	var last int64
+	for i := range q.shards {
+		last = max(last, q.shards[i].last.Load())
+	}
+	return time.Unix(0, last)
+}
+
+// drain removes the queued entries and appends them to dst in the order they
+// were pushed. It must not be called concurrently.
+func (q *queue) drain(dst []Entry) []Entry {
+	n := 0
+	for i := range q.shards {
+		s := &q.shards[i]
+
+		s.mu.Lock()
+		s.entries, s.spare = s.spare[:0], s.entries
+		s.mu.Unlock()
+
+		n += len(s.spare)
+		q.heads[i] = 0
+	}
+
+	if n == 0 {
+		return dst
+	}
+	q.taken.Add(int64(n))
+
+	// Every shard is ordered, so repeatedly taking the lowest head merges them.
+	for range n {
+		best := -1
+		for i := range q.shards {
+			s := &q.shards[i]
+			if q.heads[i] < len(s.spare) && (best < 0 || s.spare[q.heads[i]].seq < q.shards[best].spare[q.heads[best]].seq) {
+				best = i
+			}
+		}
+
+		dst = append(dst, q.shards[best].spare[q.heads[best]].Entry)
+		q.heads[best]++
+	}
+
+	for i := range q.shards {
+		clear(q.shards[i].spare)
+	}
+
+	return dst
+}
+
+// admit reports whether another entry fits below MaxQueueSize. With BlockOnFull it waits until one fits
+// and only fails once the logger stops.
+func (l *LokiLogger) admit(cfg *Config) bool {
+	limit := int64(cfg.MaxQueueSize)
+	for l.pending.Load() >= limit {
+		if !cfg.BlockOnFull {
+			return false
+		}
+
+		l.spaceMu.Lock()
+		if l.space == nil {
+			l.space = make(chan struct{})
+		}
+		space := l.space
+		l.spaceMu.Unlock()
+
+		// Entries sent since the check closed the previous channel.
+		if l.pending.Load() < limit {
+			return true
+		}
+		select {
+		case <-l.ctx.Done():
+			return false
+		case <-space:
+		}
+	}
+	return true
+}
+
+// release removes n sent or discarded entries from the pending ones and wakes the blocked writers.
+func (l *LokiLogger) release(n int) {
+	if n <= 0 {
+		return
+	}
+	l.pending.Add(int64(-n))
+
+	l.spaceMu.Lock()
+	if l.space != nil {
+		close(l.space)
+		l.space = nil
+	}
+	l.spaceMu.Unlock()
+}
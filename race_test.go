@@ -0,0 +1,111 @@
+package lokilogger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSink records the pushed entries. Its push function, if set, runs before an attempt is recorded.
+type testSink struct {
+	mu       sync.Mutex
+	push     func(ctx context.Context, attempt int) error
+	attempts int
+	entries  int
+}
+
+func (s *testSink) Push(ctx context.Context, streams []Stream) error {
+	s.mu.Lock()
+	s.attempts++
+	attempt, push := s.attempts, s.push
+	s.mu.Unlock()
+
+	if push != nil {
+		if err := push(ctx, attempt); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.entries += countEntries(streams)
+	s.mu.Unlock()
+	return nil
+}
+
+// counts returns the push attempts and the entries pushed successfully.
+func (s *testSink) counts() (attempts, entries int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts, s.entries
+}
+
+// TestConcurrentWriteFlushClose hammers the hand-off between the writers, Flush and the sender, and is
+// meant to be run with -race.
+func TestConcurrentWriteFlushClose(t *testing.T) {
+	withoutStdout(t)
+
+	sink := &testSink{}
+	l := newTestLogger(t, context.Background(), Config{Sink: sink, BatchSize: 7})
+
+	const writers, writes = 16, 200
+	var wg sync.WaitGroup
+	var written, closed sync.Map
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range writes {
+				_, err := l.Write(fmt.Appendf(nil, "writer %d line %d", w, i))
+				switch {
+				case err == nil:
+					written.Store([2]int{w, i}, true)
+				case errors.Is(err, ErrClosed):
+					closed.Store([2]int{w, i}, true)
+				default:
+					t.Errorf("Write: %v", err)
+				}
+				if i%10 == 0 {
+					l.Flush()
+				}
+			}
+		}()
+	}
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				l.Flush()
+				l.Stats()
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	var cwg sync.WaitGroup
+	for range 4 {
+		cwg.Add(1)
+		go func() {
+			defer cwg.Done()
+			if err := l.Close(context.Background()); err != nil {
+				t.Errorf("Close: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	cwg.Wait()
+
+	// Entries written before Close are all pushed, the later ones are refused.
+	var n int
+	written.Range(func(any, any) bool { n++; return true })
+	if _, entries := sink.counts(); entries != n {
+		t.Errorf("pushed %d entries, want the %d written", entries, n)
+	}
+	closed.Range(func(any, any) bool { n++; return true })
+	if n != writers*writes {
+		t.Errorf("%d writes accounted for, want %d", n, writers*writes)
+	}
+}
@@ -0,0 +1,134 @@
+package lokilogger
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy defines what happens to entries that exceed a configured limit.
+type OverflowPolicy int
+
+const (
+	// OverflowDrop drops the entry that exceeds the limit.
+	OverflowDrop OverflowPolicy = iota
+	// OverflowBlock blocks the writer until the entry fits into the limit.
+	OverflowBlock
+)
+
+// LineSizePolicy defines what happens to entries longer than Config.MaxLineSize.
+type LineSizePolicy int
+
+const (
+	// LineTruncate truncates the line and flags the entry with the "truncated" structured metadata field.
+	LineTruncate LineSizePolicy = iota
+	// LineDrop drops the entry.
+	LineDrop
+)
+
+// tokenBucket is a token bucket refilled at rate tokens per second, holding at most one second worth of
+// tokens and at least one token.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, now time.Time) *tokenBucket {
+	b := &tokenBucket{rate: rate, last: now}
+	b.tokens = b.size()
+	return b
+}
+
+// size returns the capacity of the bucket.
+func (b *tokenBucket) size() float64 {
+	return max(b.rate, 1)
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, b.size())
+	b.last = now
+}
+
+// rateLimiter limits the number of entries and bytes per second accepted by the logger.
+type rateLimiter struct {
+	mu      sync.Mutex
+	policy  OverflowPolicy
+	entries *tokenBucket
+	bytes   *tokenBucket
+}
+
+func newRateLimiter(entriesPerSec, bytesPerSec float64, policy OverflowPolicy) *rateLimiter {
+	if entriesPerSec <= 0 && bytesPerSec <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	r := &rateLimiter{policy: policy}
+	if entriesPerSec > 0 {
+		r.entries = newTokenBucket(entriesPerSec, now)
+	}
+	if bytesPerSec > 0 {
+		r.bytes = newTokenBucket(bytesPerSec, now)
+	}
+
+	return r
+}
+
+// allow reports whether an entry of size bytes may pass. With OverflowBlock it
+// waits for enough tokens and only returns false if ctx is done.
+func (r *rateLimiter) allow(ctx context.Context, size int) bool {
+	if r == nil {
+		return true
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	var wait time.Duration
+	for _, req := range []struct {
+		b *tokenBucket
+		n float64
+	}{{r.entries, 1}, {r.bytes, float64(size)}} {
+		if req.b == nil {
+			continue
+		}
+		req.b.refill(now)
+		// An entry larger than the bucket passes once the bucket is full, taking the tokens of the
+		// following seconds.
+		need := min(req.n, req.b.size())
+		if req.b.tokens >= need {
+			continue
+		}
+		if r.policy != OverflowBlock {
+			r.mu.Unlock()
+			return false
+		}
+		if d := time.Duration((need - req.b.tokens) / req.b.rate * float64(time.Second)); d > wait {
+			wait = d
+		}
+	}
+
+	// Take the tokens now; with OverflowBlock the buckets go negative and
+	// subsequent writers queue up behind this one.
+	if r.entries != nil {
+		r.entries.tokens--
+	}
+	if r.bytes != nil {
+		r.bytes.tokens -= float64(size)
+	}
+	r.mu.Unlock()
+
+	if wait == 0 {
+		return true
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
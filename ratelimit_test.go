@@ -0,0 +1,42 @@
+package lokilogger
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAdmitsEntriesLargerThanTheBucket(t *testing.T) {
+	r := newRateLimiter(0, 100, OverflowDrop)
+	ctx := context.Background()
+
+	if !r.allow(ctx, 1000) {
+		t.Fatal("entry larger than ByteRateLimit dropped with a full bucket")
+	}
+	if r.allow(ctx, 10) {
+		t.Error("entry admitted while the bucket is in debt")
+	}
+
+	// The debt of 900 bytes and a full bucket are refilled after 10 seconds.
+	r.bytes.last = r.bytes.last.Add(-10 * time.Second)
+	if !r.allow(ctx, 1000) {
+		t.Error("entry larger than ByteRateLimit dropped with a refilled bucket")
+	}
+}
+
+func TestRateLimiterBelowOneEntryPerSecond(t *testing.T) {
+	r := newRateLimiter(0.5, 0, OverflowDrop)
+	ctx := context.Background()
+
+	if !r.allow(ctx, 1) {
+		t.Fatal("first entry dropped with RateLimit 0.5")
+	}
+	if r.allow(ctx, 1) {
+		t.Error("second entry admitted right away with RateLimit 0.5")
+	}
+
+	r.entries.last = r.entries.last.Add(-2 * time.Second)
+	if !r.allow(ctx, 1) {
+		t.Error("entry dropped after 2 seconds with RateLimit 0.5")
+	}
+}
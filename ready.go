@@ -0,0 +1,137 @@
+package lokilogger
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultReadinessInterval = 5 * time.Second
+	defaultMaxBufferSize     = 10000
+)
+
+// BuildInfo describes the Loki version reported by the buildinfo endpoint.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Branch    string `json:"branch"`
+	BuildUser string `json:"buildUser"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// apiURL returns the URL of path on the Loki instance serving pushURL.
+func apiURL(pushURL, path string) (string, error) {
+	u, err := url.Parse(pushURL)
+	if err != nil {
+		return "", err
+	}
+
+	u.Path, u.RawQuery = path, ""
+
+	return u.String(), nil
+}
+
+// Ready calls the Loki /ready endpoint and returns an error unless Loki reports ready.
+func (l *LokiLogger) Ready(ctx context.Context) error {
+	return l.Client().Ready(ctx)
+}
+
+// Ping verifies connectivity to Loki and returns its build information.
+func (l *LokiLogger) Ping(ctx context.Context) (*BuildInfo, error) {
+	return l.Client().Ping(ctx)
+}
+
+// readinessLoop probes Loki while it is not ready and sends the held batches to the primary sink once it becomes ready.
+func (l *LokiLogger) readinessLoop() {
+	interval := l.config().ReadinessInterval
+	if interval <= 0 {
+		interval = defaultReadinessInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if !l.ready.Load() && l.Ready(l.ctx) == nil {
+			l.ready.Store(true)
+			if held := l.takeHeld(); len(held) > 0 {
+				l.mu.Lock()
+				primary := l.sinks[0]
+				l.mu.Unlock()
+
+				go l.push(primary, true, held)
+			}
+		}
+
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// hold buffers the streams until Loki becomes ready, spilling or dropping the oldest
+// entries once more than MaxBufferSize entries are held.
+func (l *LokiLogger) hold(streams []Stream) {
+	limit := l.config().MaxBufferSize
+	if limit <= 0 {
+		limit = defaultMaxBufferSize
+	}
+
+	l.heldMu.Lock()
+
+	l.held = append(l.held, streams...)
+
+	n := 0
+	for _, s := range l.held {
+		n += len(s.Entries)
+	}
+
+	var overflow []Stream
+	dropped := 0
+	for n > limit && len(l.held) > 0 {
+		drop := min(n-limit, len(l.held[0].Entries))
+		st := l.held[0]
+		st.Entries = st.Entries[:drop]
+		overflow = append(overflow, st)
+		l.held[0].Entries = l.held[0].Entries[drop:]
+		dropped += drop
+		n -= drop
+		if len(l.held[0].Entries) == 0 {
+			l.held = l.held[1:]
+		}
+	}
+
+	l.heldMu.Unlock()
+
+	if l.spill(overflow) {
+		return
+	}
+
+	if dropped > 0 {
+		l.counters.dropped.Add(int64(dropped))
+		l.logf("warn", "Dropped %d held entries: buffer is full", dropped)
+	}
+}
+
+// takeHeld returns and clears the held streams.
+func (l *LokiLogger) takeHeld() []Stream {
+	l.heldMu.Lock()
+	defer l.heldMu.Unlock()
+
+	held := l.held
+	l.held = nil
+
+	return held
+}
+
+// heldLen returns the number of held entries.
+func (l *LokiLogger) heldLen() int {
+	l.heldMu.Lock()
+	defer l.heldMu.Unlock()
+
+	return countEntries(l.held)
+}
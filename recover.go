@@ -0,0 +1,65 @@
+package lokilogger
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// RecoverAndLog recovers a panic using the logger created by Init, see LokiLogger.RecoverAndLog.
+//
+//	defer lokilogger.RecoverAndLog(ctx)
+func RecoverAndLog(ctx context.Context) {
+	if r := recover(); r != nil {
+		if l := Default(); l != nil {
+			l.logPanic(ctx, r)
+		}
+	}
+}
+
+// RecoverAndRepanic logs a panic like RecoverAndLog using the logger created by Init and then panics again.
+//
+//	defer lokilogger.RecoverAndRepanic(ctx)
+func RecoverAndRepanic(ctx context.Context) {
+	if r := recover(); r != nil {
+		if l := Default(); l != nil {
+			l.logPanic(ctx, r)
+		}
+		panic(r)
+	}
+}
+
+// RecoverAndLog must be deferred. It recovers a panic, logs the panic value and
+// the stack trace at error level and synchronously sends the collected logs, so
+// that they reach Loki before the process exits, waiting at most 5 seconds.
+// The trace IDs are taken from ctx.
+//
+//	defer l.RecoverAndLog(ctx)
+func (l *LokiLogger) RecoverAndLog(ctx context.Context) {
+	if r := recover(); r != nil {
+		l.logPanic(ctx, r)
+	}
+}
+
+// RecoverAndRepanic logs a panic like RecoverAndLog and then panics again.
+//
+//	defer l.RecoverAndRepanic(ctx)
+func (l *LokiLogger) RecoverAndRepanic(ctx context.Context) {
+	if r := recover(); r != nil {
+		l.logPanic(ctx, r)
+		panic(r)
+	}
+}
+
+// logPanic logs the recovered value with the stack trace and waits until the logs are sent.
+func (l *LokiLogger) logPanic(ctx context.Context, r any) {
+	l.LogCtx(ctx, "error", fmt.Sprintf("panic: %v\n\n%s", r, debug.Stack()))
+
+	// The context of a failing request may already be cancelled.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultDrainTimeout)
+	defer cancel()
+
+	if err := l.drain(ctx); err != nil {
+		l.logf("error", "Error loki flush after panic: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package lokilogger
+
+import "regexp"
+
+// Redactor rewrites a log line before it leaves the process.
+type Redactor func(line string) string
+
+// redactedText replaces sensitive values removed by the built-in redactors.
+const redactedText = "[REDACTED]"
+
+var (
+	// RedactCreditCards masks card numbers: sequences of 13 to 19 digits optionally separated by spaces
+	// or dashes that pass the Luhn check, so that IDs and timestamps are kept.
+	RedactCreditCards Redactor = redactCardNumbers
+	// RedactEmails masks e-mail addresses.
+	RedactEmails = MustRegexRedactor(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`, redactedText)
+	// RedactBearerTokens masks the credentials of "Bearer <token>" authorization values.
+	RedactBearerTokens = MustRegexRedactor(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`, "${1}"+redactedText)
+)
+
+// cardNumber matches the candidates of RedactCreditCards.
+var cardNumber = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+
+func redactCardNumbers(line string) string {
+	return cardNumber.ReplaceAllStringFunc(line, func(m string) string {
+		if !luhnValid(m) {
+			return m
+		}
+		return redactedText
+	})
+}
+
+// luhnValid reports whether the digits of s, ignoring separators, pass the Luhn checksum.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// RegexRedactor returns a Redactor replacing every match of pattern with repl.
+// Inside repl, $ signs are interpreted as in regexp.Regexp.ReplaceAllString.
+func RegexRedactor(pattern, repl string) (Redactor, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(line string) string {
+		return re.ReplaceAllString(line, repl)
+	}, nil
+}
+
+// MustRegexRedactor is like RegexRedactor but panics if the pattern cannot be parsed.
+func MustRegexRedactor(pattern, repl string) Redactor {
+	r, err := RegexRedactor(pattern, repl)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// redact applies all configured redactors to the line in order.
+func (l *LokiLogger) redact(line string) string {
+	for _, r := range l.config().Redactors {
+		line = r(line)
+	}
+	return line
+}
+
+// redactMetadata applies the redactors to the structured metadata values, e.g. of slog attributes, context
+// extractors and parsed logfmt pairs. The metadata is copied, as it may be shared with the caller.
+func redactMetadata(redactors []Redactor, md map[string]string) map[string]string {
+	if len(redactors) == 0 || len(md) == 0 {
+		return md
+	}
+
+	redacted := make(map[string]string, len(md))
+	for k, v := range md {
+		for _, r := range redactors {
+			v = r(v)
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
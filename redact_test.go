@@ -0,0 +1,35 @@
+package lokilogger
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestRedactCreditCards(t *testing.T) {
+	for _, tt := range []struct{ line, want string }{
+		{"card 4111 1111 1111 1111 declined", "card [REDACTED] declined"},
+		{"card 5500-0000-0000-0004", "card [REDACTED]"},
+		{"order 1234567890123 shipped", "order 1234567890123 shipped"},
+		{"ts=1718000000000000", "ts=1718000000000000"},
+	} {
+		if got := RedactCreditCards(tt.line); got != tt.want {
+			t.Errorf("RedactCreditCards(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestRedactMetadata(t *testing.T) {
+	sink := entrySink{entries: make(chan Entry, 1)}
+	l := newTestLogger(t, context.Background(), Config{Sink: sink, Redactors: []Redactor{RedactEmails}})
+
+	slog.New(l.Handler()).Info("signup", "email", "bob@example.com", "plan", "free")
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	e := <-sink.entries
+	if e.Metadata["email"] != "[REDACTED]" || e.Metadata["plan"] != "free" {
+		t.Errorf("shipped metadata %v", e.Metadata)
+	}
+}
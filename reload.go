@@ -0,0 +1,169 @@
+package lokilogger
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// UpdateConfig replaces the configuration of a running logger, e.g. the batch
+// size, flush interval, labels, minimum level, sampling, rate limits and
+// endpoints. The pending batch is sent with the previous configuration first,
+// and batches already being sent complete against their original sinks, so
+// no logs are lost during the swap. The HTTP transport settings (TLSConfig,
+// LoadBalance, ProxyURL, DialContext, SigV4, OAuth2, AccessTokenFile, RequestTimeout, MaxIdleConns, MaxConnsPerHost, IdleConnTimeout,
+// ExpectContinue and a unix socket URL), ReadinessProbe, SpillDir and Clock can't be changed at runtime;
+// an error is returned unless they are passed unchanged. With AdaptiveBatching the adapted batch size and
+// flush interval are kept within the new bounds.
+func (l *LokiLogger) UpdateConfig(cfg Config) error {
+	cfg.setDefaults()
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	cur := l.config()
+	if name := changedTransportSetting(cur, &cfg); name != "" {
+		return fmt.Errorf("%s can't be changed at runtime", name)
+	}
+	if a := cfg.AdaptiveBatching; a != nil && cur.AdaptiveBatching != nil {
+		cfg.BatchSize = min(max(cur.BatchSize, a.MinBatchSize), a.MaxBatchSize)
+		cfg.FlushInterval = min(max(cur.FlushInterval, a.MinFlushInterval), a.MaxFlushInterval)
+	}
+
+	if cfg.ReadinessProbe != cur.ReadinessProbe {
+		return fmt.Errorf("ReadinessProbe can't be changed at runtime")
+	}
+	if cfg.SpillDir != cur.SpillDir {
+		return fmt.Errorf("SpillDir can't be changed at runtime")
+	}
+	if cfg.Clock != cur.Clock {
+		return fmt.Errorf("Clock can't be changed at runtime")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Ship the pending batch and the buffers of BatchBy with the previous settings so that nothing is
+	// lost during the swap.
+	l.takeQueued()
+	l.logs = l.bucket(l.config(), l.logs, true)
+	l.prepareLogs(true)
+
+	// The streams seen remain in Loki, so the guard keeps tracking them unless the limit changes.
+	if cfg.MaxStreams != l.config().MaxStreams {
+		l.guard.Store(newCardinalityGuard(cfg.MaxStreams))
+	}
+	l.cfg.Store(&cfg)
+	l.sampler = newSampler(cfg.SampleRates)
+	l.deduper = newDeduper(cfg.DedupWindow)
+	l.limiter.Store(newRateLimiter(cfg.RateLimit, cfg.ByteRateLimit, cfg.Overflow))
+	l.retries.Store(newRetryBudget(cfg.RetryBudget, cfg.RetryPolicy))
+	l.breaker.Store(newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown))
+	l.labels = staticLabels(cfg)
+	l.sinks = l.newSinks(&cfg)
+	l.routes = l.newRouteSinks(&cfg)
+
+	l.resetAutoFlushTimer()
+
+	if cfg.AdaptiveBatching != nil {
+		l.startAdapting()
+	}
+
+	return nil
+}
+
+// changedTransportSetting returns the name of the first setting of the HTTP client differing between the
+// configurations, or "" if there is none. The client is created once by New.
+func changedTransportSetting(cur, cfg *Config) string {
+	curSocket, _ := unixSocketPath(cur.URL)
+	socket, _ := unixSocketPath(cfg.URL)
+
+	switch {
+	case cfg.TLSConfig != cur.TLSConfig:
+		return "TLSConfig"
+	case cfg.LoadBalance != cur.LoadBalance || cfg.ResolveInterval != cur.ResolveInterval:
+		return "LoadBalance"
+	case cfg.ProxyURL != cur.ProxyURL:
+		return "ProxyURL"
+	case reflect.ValueOf(cfg.DialContext).Pointer() != reflect.ValueOf(cur.DialContext).Pointer():
+		return "DialContext"
+	case socket != curSocket:
+		return "URL of the unix socket"
+	case cfg.SigV4 != cur.SigV4:
+		return "SigV4"
+	case cfg.OAuth2 != cur.OAuth2:
+		return "OAuth2"
+	case cfg.AccessTokenFile != cur.AccessTokenFile || cfg.AccessTokenRefresh != cur.AccessTokenRefresh:
+		return "AccessTokenFile"
+	case cfg.RequestTimeout != cur.RequestTimeout:
+		return "RequestTimeout"
+	case cfg.MaxIdleConns != cur.MaxIdleConns || cfg.MaxConnsPerHost != cur.MaxConnsPerHost || cfg.IdleConnTimeout != cur.IdleConnTimeout:
+		return "MaxIdleConns, MaxConnsPerHost or IdleConnTimeout"
+	case cfg.ExpectContinue != cur.ExpectContinue:
+		return "ExpectContinue"
+	}
+	return ""
+}
+
+// WatchConfig polls the configuration file at path every interval and applies
+// it with UpdateConfig when it changes. Settings that can't be expressed in a
+// file, such as sinks, middlewares and redactors, are kept from the current
+// configuration. Watching stops when the logger's context is done.
+func (l *LokiLogger) WatchConfig(path string, interval time.Duration) {
+	var last os.FileInfo
+	if fi, err := os.Stat(path); err == nil {
+		last = fi
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-l.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			fi, err := os.Stat(path)
+			if err != nil || last != nil && fi.ModTime().Equal(last.ModTime()) && fi.Size() == last.Size() {
+				continue
+			}
+			last = fi
+
+			if err := l.reloadConfig(path); err != nil {
+				l.logf("error", "Error loki reload config: %v", err)
+			}
+		}
+	}()
+}
+
+// reloadConfig loads the configuration file and applies it, keeping code-only settings.
+func (l *LokiLogger) reloadConfig(path string) error {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	cur := l.config()
+	cfg.Redactors = cur.Redactors
+	cfg.Middlewares = cur.Middlewares
+	cfg.TraceExtractor = cur.TraceExtractor
+	cfg.ContextExtractors = cur.ContextExtractors
+	cfg.Sink = cur.Sink
+	cfg.Sinks = cur.Sinks
+	cfg.TLSConfig = cur.TLSConfig
+	cfg.InternalLogger = cur.InternalLogger
+	cfg.Clock = cur.Clock
+	cfg.OnError = cur.OnError
+	cfg.DialContext = cur.DialContext
+	cfg.SigV4 = cur.SigV4
+	cfg.OAuth2 = cur.OAuth2
+	cfg.AccessTokenFile = cur.AccessTokenFile
+	cfg.AccessTokenRefresh = cur.AccessTokenRefresh
+	cfg.DryRunOutput = cur.DryRunOutput
+
+	return l.UpdateConfig(cfg)
+}
@@ -0,0 +1,74 @@
+package lokilogger
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestUpdateConfigShipsBufferedEntries checks that the entries buffered by BatchBy are shipped with the
+// previous configuration.
+func TestUpdateConfigShipsBufferedEntries(t *testing.T) {
+	sink, next := &testSink{}, &testSink{}
+	l := newTestLogger(t, context.Background(), Config{Sink: sink, BatchBy: BatchPerLevel})
+
+	for range 5 {
+		l.ship(Entry{Level: "info", Line: "hello"})
+		l.ship(Entry{Level: "error", Line: "failed"})
+	}
+	l.sendFull()
+
+	if err := l.UpdateConfig(Config{URL: "http://loki.invalid", Sink: next, FlushInterval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, entries := sink.counts(); entries != 10 {
+		t.Errorf("pushed %d entries to the previous sink, want 10", entries)
+	}
+	if _, entries := next.counts(); entries != 0 {
+		t.Errorf("pushed %d entries to the new sink, want 0", entries)
+	}
+	if n := l.Stats().QueueLen; n != 0 {
+		t.Errorf("%d entries left in the buffers", n)
+	}
+}
+
+func TestUpdateConfigRejectsTransportChanges(t *testing.T) {
+	l := newTestLogger(t, context.Background(), Config{Sink: nopSink{}})
+	defer l.Close(context.Background())
+
+	cfg := *l.config()
+	cfg.TLSConfig = &tls.Config{}
+	if err := l.UpdateConfig(cfg); err == nil || !strings.Contains(err.Error(), "TLSConfig") {
+		t.Errorf("UpdateConfig with a new TLSConfig: %v, want an error", err)
+	}
+
+	cfg = *l.config()
+	cfg.MinLevel = "warn"
+	if err := l.UpdateConfig(cfg); err != nil {
+		t.Errorf("UpdateConfig with the same transport: %v", err)
+	}
+}
+
+func TestUpdateConfigKeepsAdaptedBatching(t *testing.T) {
+	l := newTestLogger(t, context.Background(), Config{Sink: nopSink{}, AdaptiveBatching: &AdaptiveBatching{}})
+	defer l.Close(context.Background())
+
+	// As adapted to a high rate.
+	cfg := *l.config()
+	cfg.BatchSize, cfg.FlushInterval = 800, 8*time.Second
+	l.cfg.Store(&cfg)
+
+	cfg.AdaptiveBatching = &AdaptiveBatching{MaxBatchSize: 500}
+	if err := l.UpdateConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if c := l.config(); c.BatchSize != 500 || c.FlushInterval != 8*time.Second {
+		t.Errorf("got BatchSize %d and FlushInterval %s, want 500 and 8s", c.BatchSize, c.FlushInterval)
+	}
+}
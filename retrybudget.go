@@ -0,0 +1,120 @@
+package lokilogger
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RetryPolicy selects which batches retry first once Config.RetryBudget is exhausted.
+type RetryPolicy int
+
+const (
+	// RetryFIFO makes the retries wait for the budget in the order they were requested.
+	RetryFIFO RetryPolicy = iota
+	// RetryNewestFirst makes a batch waiting for the budget give up its retries as soon as a newer batch
+	// needs one, so that the freshest logs are retried first during an outage. The batches giving up are
+	// held, spilled or dead-lettered like batches out of retries.
+	RetryNewestFirst
+)
+
+// retryBudget limits the retries per second across all batches and sinks.
+type retryBudget struct {
+	mu      sync.Mutex
+	policy  RetryPolicy
+	rate    float64
+	tokens  float64
+	last    time.Time
+	waiting uint64        // Newest batch waiting for a token with RetryNewestFirst.
+	wake    chan struct{} // Closed when a newer batch starts waiting.
+}
+
+func newRetryBudget(perSec float64, policy RetryPolicy) *retryBudget {
+	if perSec <= 0 {
+		return nil
+	}
+	return &retryBudget{policy: policy, rate: perSec, tokens: max(perSec, 1), last: time.Now(), wake: make(chan struct{})}
+}
+
+// refill adds the tokens accrued since the last call, holding at most one second worth of them, or one.
+func (b *retryBudget) refill(now time.Time) {
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, max(b.rate, 1))
+	b.last = now
+}
+
+// wait takes a token for a retry of the batch, the n-th pushed, waiting for it if needed. It reports false
+// if ctx is done or, with RetryNewestFirst unless keep is set, a newer batch needs a retry meanwhile.
+func (b *retryBudget) wait(ctx context.Context, batch uint64, keep bool) bool {
+	if b == nil {
+		return true
+	}
+
+	if b.policy != RetryNewestFirst || keep {
+		// The bucket goes negative, so the following retries queue up behind this one.
+		b.mu.Lock()
+		b.refill(time.Now())
+		b.tokens--
+		wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		return sleepCtx(ctx, wait)
+	}
+
+	// The batch stops waiting when it gets a token, a newer batch waits or ctx is done.
+	defer func() {
+		b.mu.Lock()
+		if b.waiting == batch {
+			b.waiting = 0
+		}
+		b.mu.Unlock()
+	}()
+
+	for {
+		b.mu.Lock()
+		if batch < b.waiting {
+			b.mu.Unlock()
+			return false
+		}
+		b.refill(time.Now())
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return true
+		}
+		if batch > b.waiting {
+			b.waiting = batch
+			close(b.wake)
+			b.wake = make(chan struct{})
+		}
+		wake := b.wake
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return false
+		case <-wake:
+		case <-t.C:
+		}
+		t.Stop()
+	}
+}
+
+// sleepCtx waits for d and reports false if ctx is done first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,47 @@
+package lokilogger
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRetryBudgetNewestFirst(t *testing.T) {
+	b := newRetryBudget(20, RetryNewestFirst)
+	b.tokens = 0
+
+	// The older batch gives up its retry once the newer one waits.
+	older := make(chan bool)
+	go func() { older <- b.wait(context.Background(), 1, false) }()
+	time.Sleep(10 * time.Millisecond)
+	if !b.wait(context.Background(), 2, false) {
+		t.Fatal("newer batch gave up")
+	}
+	if <-older {
+		t.Error("older batch kept waiting for the budget")
+	}
+
+	// With keep the older batch retries regardless.
+	if !b.wait(context.Background(), 1, true) {
+		t.Error("kept batch gave up")
+	}
+}
+
+func TestRetryBudgetCancelledWaitClearsWaiting(t *testing.T) {
+	b := newRetryBudget(20, RetryNewestFirst)
+	b.tokens = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() { done <- b.wait(ctx, 2, false) }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if <-done {
+		t.Fatal("cancelled wait took a token")
+	}
+
+	// Older batches are no longer turned away by the batch that gave up.
+	if !b.wait(context.Background(), 1, false) {
+		t.Error("older batch gave up after the newer one was cancelled")
+	}
+}
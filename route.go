@@ -0,0 +1,118 @@
+package lokilogger
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Route directs the entries matching all of its set matchers to a tenant, another Loki endpoint
+// or a sink, e.g. security audit lines to a locked-down tenant. The first matching route of
+// Config.Routes applies; entries matching none go to the default sinks.
+type Route struct {
+	Level   string            // Matches entries at the level.
+	Labels  map[string]string // Matches entries with all the extra stream labels.
+	Message *regexp.Regexp    // Matches entries whose line matches.
+
+	// Tenant routes the entries to the tenant, see TenantLabel.
+	Tenant string
+	// URL pushes the entries to another Loki endpoint instead of the default sinks.
+	URL string
+	// Sink pushes the entries to the sink instead of the default sinks.
+	Sink Sink
+}
+
+// matches reports whether the entry matches all the matchers of the route.
+func (r *Route) matches(e Entry) bool {
+	if r.Level != "" && r.Level != e.Level {
+		return false
+	}
+	for k, v := range r.Labels {
+		if got, ok := e.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return r.Message == nil || r.Message.MatchString(e.Line)
+}
+
+// validate checks that the route has a destination.
+func (r *Route) validate() error {
+	if r.Tenant == "" && r.URL == "" && r.Sink == nil {
+		return fmt.Errorf("route needs a Tenant, URL or Sink")
+	}
+	if r.URL != "" && r.Sink != nil {
+		return fmt.Errorf("route can't have both a URL and a Sink")
+	}
+	if r.URL != "" {
+		return validateURL(r.URL)
+	}
+	return nil
+}
+
+// route applies the first route matching the entry. Entries routed to a URL or sink
+// carry the 1-based index of the route, so that they are batched separately.
+func route(cfg *Config, e Entry) Entry {
+	for i := range cfg.Routes {
+		r := &cfg.Routes[i]
+		if !r.matches(e) {
+			continue
+		}
+
+		if r.Tenant != "" {
+			e.Tenant = r.Tenant
+		}
+		if r.URL != "" || r.Sink != nil {
+			e.route = i + 1
+		}
+		break
+	}
+
+	return e
+}
+
+// newRouteSinks returns the sinks of the routes, nil for routes without a URL or sink.
+func (l *LokiLogger) newRouteSinks(cfg *Config) []Sink {
+	if len(cfg.Routes) == 0 {
+		return nil
+	}
+
+	sinks := make([]Sink, len(cfg.Routes))
+	for i, r := range cfg.Routes {
+		switch {
+		case cfg.DryRun && (r.URL != "" || r.Sink != nil):
+			sinks[i] = &dryRunSink{w: cfg.DryRunOutput, url: httpURL(r.URL, cfg.Protocol, cfg.PushPath), protocol: cfg.Protocol}
+		case r.Sink != nil:
+			sinks[i] = r.Sink
+		case r.URL != "":
+			sinks[i] = l.lokiSink(cfg, r.URL)
+		}
+	}
+	return sinks
+}
+
+// splitStreams groups the streams by the key, keeping their order within each group.
+func splitStreams[K comparable](streams []Stream, key func(Stream) K) [][]Stream {
+	mixed := false
+	for _, s := range streams {
+		if key(s) != key(streams[0]) {
+			mixed = true
+			break
+		}
+	}
+	if !mixed {
+		return [][]Stream{streams}
+	}
+
+	var groups [][]Stream
+	index := make(map[K]int)
+	for _, s := range streams {
+		i, ok := index[key(s)]
+		if !ok {
+			i = len(groups)
+			index[key(s)] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], s)
+	}
+
+	return groups
+}
@@ -0,0 +1,44 @@
+package lokilogger
+
+import "strconv"
+
+// sampler keeps 1 in N entries per level and counts the entries it drops.
+// The number of dropped entries is attached to the next kept entry of the
+// same level as the "sampled" structured metadata field.
+type sampler struct {
+	rates   map[string]int
+	seen    map[string]int
+	dropped map[string]int
+}
+
+func newSampler(rates map[string]int) *sampler {
+	return &sampler{
+		rates:   rates,
+		seen:    make(map[string]int),
+		dropped: make(map[string]int),
+	}
+}
+
+// sample reports whether the entry should be kept.
+func (s *sampler) sample(e Entry) (Entry, bool) {
+	n := s.rates[e.Level]
+	if n <= 1 {
+		return e, true
+	}
+
+	s.seen[e.Level]++
+	if s.seen[e.Level]%n != 1 {
+		s.dropped[e.Level]++
+		return e, false
+	}
+
+	if dropped := s.dropped[e.Level]; dropped > 0 {
+		if e.Metadata == nil {
+			e.Metadata = make(map[string]string)
+		}
+		e.Metadata["sampled"] = strconv.Itoa(dropped)
+		s.dropped[e.Level] = 0
+	}
+
+	return e, true
+}
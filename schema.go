@@ -0,0 +1,208 @@
+package lokilogger
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSchemaViolation is reported to Config.OnError for the entries rejected by a Schema with SchemaReject.
+var ErrSchemaViolation = errors.New("schema violation")
+
+// schemaViolationKey is the structured metadata field listing the violations of an entry with SchemaFlag.
+const schemaViolationKey = "schema_violation"
+
+// SchemaPolicy selects what happens to entries violating a Schema.
+type SchemaPolicy int
+
+const (
+	// SchemaFlag ships the entries with the violations listed in the schema_violation metadata field,
+	// e.g. "missing request_id; label user not allowed", so that they can be found in Grafana.
+	SchemaFlag SchemaPolicy = iota
+	// SchemaFix moves labels not allowed to structured metadata, drops fields with values of the wrong
+	// type and adds missing required fields with the zero value of their type, or an empty string.
+	SchemaFix
+	// SchemaReject drops the entries, counting them in Stats().Dropped, and reports them to OnError with
+	// an error wrapping ErrSchemaViolation.
+	SchemaReject
+)
+
+// FieldType is the type of the values of a structured metadata field in a Schema.
+type FieldType int
+
+const (
+	FieldString   FieldType = iota // Any value.
+	FieldInt                       // Decimal integer, e.g. 42.
+	FieldFloat                     // Floating-point number, e.g. 0.25.
+	FieldBool                      // true or false.
+	FieldDuration                  // Go duration, e.g. 1.5s.
+	FieldTime                      // RFC 3339 timestamp.
+)
+
+// fieldTypes maps the names of the types in FileConfig to the types.
+var fieldTypes = map[string]FieldType{
+	"string":   FieldString,
+	"int":      FieldInt,
+	"float":    FieldFloat,
+	"bool":     FieldBool,
+	"duration": FieldDuration,
+	"time":     FieldTime,
+}
+
+// Schema describes the fields and labels the entries must have, helping organizations to keep their
+// logs consistent across teams.
+type Schema struct {
+	// Required are the fields every entry must carry as structured metadata or extra labels.
+	Required []string
+	// Labels are the allowed keys of the extra labels. Any labels are allowed when empty.
+	Labels []string
+	// Types are the types of the values of structured metadata fields.
+	Types map[string]FieldType
+	// Policy selects what happens to violating entries, SchemaFlag by default.
+	Policy SchemaPolicy
+}
+
+// validate reports the first invalid setting of the schema.
+func (s *Schema) validate() error {
+	if s.Policy < SchemaFlag || s.Policy > SchemaReject {
+		return fmt.Errorf("invalid Schema.Policy %d", s.Policy)
+	}
+	for k, t := range s.Types {
+		if t < FieldString || t > FieldTime {
+			return fmt.Errorf("invalid Schema.Types[%q] %d", k, t)
+		}
+	}
+	return nil
+}
+
+// enforce checks the entry against the schema, returning the entry to ship and its violations. It
+// reports false if the entry is rejected.
+func (s *Schema) enforce(e Entry) (Entry, []string, bool) {
+	if s == nil {
+		return e, nil, true
+	}
+
+	var violations, labels, fields []string
+	for _, k := range slices.Sorted(maps.Keys(e.Labels)) {
+		if len(s.Labels) > 0 && !slices.Contains(s.Labels, k) {
+			violations = append(violations, "label "+k+" not allowed")
+			labels = append(labels, k)
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(e.Metadata)) {
+		if t, ok := s.Types[k]; ok && !t.matches(e.Metadata[k]) {
+			violations = append(violations, "field "+k+" not "+t.String())
+			fields = append(fields, k)
+		}
+	}
+	for _, k := range s.Required {
+		_, ok := e.Metadata[k]
+		if _, label := e.Labels[k]; !ok && !label {
+			violations = append(violations, "missing "+k)
+		}
+	}
+	if len(violations) == 0 {
+		return e, nil, true
+	}
+	if s.Policy == SchemaReject {
+		return e, violations, false
+	}
+
+	// The labels and metadata may be shared with the writer.
+	md := maps.Clone(e.Metadata)
+	if md == nil {
+		md = make(map[string]string)
+	}
+
+	switch s.Policy {
+	case SchemaFix:
+		if len(labels) > 0 {
+			e.Labels = maps.Clone(e.Labels)
+			for _, k := range labels {
+				md[k] = e.Labels[k]
+				delete(e.Labels, k)
+			}
+		}
+		for _, k := range fields {
+			delete(md, k)
+		}
+		for _, k := range s.Required {
+			_, ok := md[k]
+			if _, label := e.Labels[k]; !ok && !label {
+				md[k] = s.Types[k].zero()
+			}
+		}
+	default:
+		md[schemaViolationKey] = strings.Join(violations, "; ")
+	}
+	e.Metadata = md
+
+	return e, violations, true
+}
+
+// matches reports whether the value has the type.
+func (t FieldType) matches(v string) bool {
+	var err error
+	switch t {
+	case FieldInt:
+		_, err = strconv.ParseInt(v, 10, 64)
+	case FieldFloat:
+		_, err = strconv.ParseFloat(v, 64)
+	case FieldBool:
+		_, err = strconv.ParseBool(v)
+	case FieldDuration:
+		_, err = time.ParseDuration(v)
+	case FieldTime:
+		_, err = time.Parse(time.RFC3339Nano, v)
+	}
+	return err == nil
+}
+
+// zero returns the zero value of the type.
+func (t FieldType) zero() string {
+	switch t {
+	case FieldInt, FieldFloat:
+		return "0"
+	case FieldBool:
+		return "false"
+	case FieldDuration:
+		return "0s"
+	case FieldTime:
+		return time.Time{}.Format(time.RFC3339)
+	}
+	return ""
+}
+
+// String returns the name of the type used in violations.
+func (t FieldType) String() string {
+	switch t {
+	case FieldInt:
+		return "int"
+	case FieldFloat:
+		return "float"
+	case FieldBool:
+		return "bool"
+	case FieldDuration:
+		return "duration"
+	case FieldTime:
+		return "time"
+	}
+	return "string"
+}
+
+// onSchemaViolation counts an entry rejected by the schema as dropped and reports it to the OnError hook.
+// The entry never reaches a sink, so the FailedPush has no Sink and Attempt 0.
+func (l *LokiLogger) onSchemaViolation(violations []string) {
+	l.counters.dropped.Add(1)
+
+	err := fmt.Errorf("%w: %s", ErrSchemaViolation, strings.Join(violations, "; "))
+	l.debugf("Entry rejected: %v", err)
+
+	if hook := l.config().OnError; hook != nil {
+		hook(FailedPush{Entries: 1, Final: true, Err: err})
+	}
+}
@@ -0,0 +1,81 @@
+package lokilogger
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestSchemaEnforce(t *testing.T) {
+	entry := Entry{
+		Line:     "checkout",
+		Labels:   map[string]string{"team": "payments", "user": "bob"},
+		Metadata: map[string]string{"took": "fast"},
+	}
+	schema := Schema{
+		Required: []string{"request_id", "team"},
+		Labels:   []string{"team"},
+		Types:    map[string]FieldType{"took": FieldDuration, "request_id": FieldInt},
+	}
+	violations := "label user not allowed; field took not duration; missing request_id"
+
+	for _, tt := range []struct {
+		policy   SchemaPolicy
+		ok       bool
+		labels   map[string]string
+		metadata map[string]string
+	}{
+		{SchemaFlag, true, entry.Labels, map[string]string{"took": "fast", schemaViolationKey: violations}},
+		{SchemaFix, true, map[string]string{"team": "payments"}, map[string]string{"user": "bob", "request_id": "0"}},
+		{SchemaReject, false, entry.Labels, entry.Metadata},
+	} {
+		schema.Policy = tt.policy
+		e, got, ok := schema.enforce(entry)
+
+		if ok != tt.ok || len(got) != 3 {
+			t.Errorf("policy %d: got %v, %t, want 3 violations, %t", tt.policy, got, ok, tt.ok)
+		}
+		if !maps.Equal(e.Labels, tt.labels) || !maps.Equal(e.Metadata, tt.metadata) {
+			t.Errorf("policy %d: got labels %v and metadata %v, want %v and %v", tt.policy, e.Labels, e.Metadata, tt.labels, tt.metadata)
+		}
+	}
+
+	// The entry of the writer is left untouched.
+	if len(entry.Labels) != 2 || len(entry.Metadata) != 1 {
+		t.Errorf("entry modified: %+v", entry)
+	}
+}
+
+func TestSchemaRejectDropsEntries(t *testing.T) {
+	withoutStdout(t)
+
+	failed := make(chan FailedPush, 1)
+	sink := &testSink{}
+	l := newTestLogger(t, context.Background(), Config{
+		Sink:    sink,
+		Schema:  &Schema{Required: []string{"request_id"}, Policy: SchemaReject},
+		OnError: func(fp FailedPush) { failed <- fp },
+	})
+
+	l.Write([]byte("hello"))
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case fp := <-failed:
+		if fp.Sink != nil || fp.Entries != 1 || !fp.Final || !errors.Is(fp.Err, ErrSchemaViolation) {
+			t.Errorf("reported %+v", fp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("rejected entry not reported")
+	}
+	if _, entries := sink.counts(); entries != 0 {
+		t.Errorf("pushed %d entries, want 0", entries)
+	}
+	if dropped := l.Stats().Dropped; dropped != 1 {
+		t.Errorf("dropped %d entries, want 1", dropped)
+	}
+}
@@ -0,0 +1,214 @@
+package lokilogger
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const defaultDrainTimeout = 5 * time.Second
+
+// errDrainTimeout is the cause of the pushes cancelled because a drain ran out of time.
+var errDrainTimeout = errors.New("shutdown timed out")
+
+// ErrClosed is returned by writes to a logger after Close or once its context is done.
+var ErrClosed = errors.New("logger is closed")
+
+// CancelPolicy selects what happens to the collected logs once the context of the logger is done.
+type CancelPolicy int
+
+const (
+	// CancelFlush sends the collected logs like Close, waiting at most 5 seconds for them.
+	CancelFlush CancelPolicy = iota
+	// CancelDiscard drops the collected logs and aborts the pushes in progress, e.g. when the logs were
+	// already sent with Close or losing them is preferred to delaying the exit. Spilled batches are kept.
+	CancelDiscard
+)
+
+// Shutdown sends the collected logs and waits until all batches being sent are
+// done or ctx is done, e.g. before the process exits. The logger keeps accepting
+// logs, see Close to stop it.
+func (l *LokiLogger) Shutdown(ctx context.Context) error {
+	return l.drain(ctx)
+}
+
+// Close stops the logger and sends the collected logs. It waits until the
+// batches being sent are done or ctx is done, aborting them then. Every entry
+// written before Close is called is pushed unless ctx is done first; entries
+// failing to be pushed are held, spilled or dead-lettered as usual. Writes
+// afterwards return ErrClosed or are dropped, and the background goroutines of
+// the logger exit. Close takes over from CancelPolicy if the context of the
+// logger is done meanwhile. Calling Close again waits for the batches again.
+func (l *LokiLogger) Close(ctx context.Context) error {
+	l.closing.Store(true)
+	l.cancel()
+
+	select {
+	case <-l.stopped:
+	case <-ctx.Done():
+		l.abortPushes()
+		return ctx.Err()
+	}
+
+	err := l.drain(ctx)
+	l.client.CloseIdleConnections()
+	return err
+}
+
+// finish handles the collected logs once the context of the logger is done,
+// according to CancelPolicy.
+func (l *LokiLogger) finish() {
+	defer l.client.CloseIdleConnections()
+
+	if l.config().CancelPolicy == CancelDiscard {
+		l.discard()
+		l.abortPushes()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultDrainTimeout)
+	defer cancel()
+
+	if err := l.drain(ctx); err != nil {
+		l.logf("error", "Error loki flush on cancellation: %v", err)
+	}
+}
+
+// discard drops the collected logs.
+func (l *LokiLogger) discard() {
+	l.flushMultiline()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.takeQueued()
+	if l.coalescer.timer != nil {
+		l.coalescer.timer.Stop()
+		l.coalescer.timer = nil
+	}
+	n := len(l.logs) + len(l.coalescer.entries) + l.bucketed
+	l.logs, l.coalescer.entries = l.logs[:0], nil
+	clear(l.buckets)
+	l.bucketed = 0
+
+	l.counters.dropped.Add(int64(n))
+	l.release(n)
+}
+
+// ShutdownOnDone calls Shutdown with the timeout once ctx is done, e.g. a
+// context from signal.NotifyContext. The returned channel is closed when the
+// logs are sent, so that main can wait for it before exiting:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//	drained := l.ShutdownOnDone(ctx, 5*time.Second)
+//	...
+//	<-drained
+func (l *LokiLogger) ShutdownOnDone(ctx context.Context, timeout time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		l.shutdownTimeout(timeout)
+	}()
+
+	return done
+}
+
+// HandleSignals installs a handler for the signals, SIGINT and SIGTERM by
+// default, that calls Shutdown with the timeout and then raises the signal
+// again with the default behaviour, terminating the process. The returned
+// function removes the handler.
+func (l *LokiLogger) HandleSignals(timeout time.Duration, signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	quit := make(chan struct{})
+	go func() {
+		select {
+		case <-quit:
+			return
+		case sig := <-ch:
+			l.shutdownTimeout(timeout)
+
+			signal.Reset(signals...)
+			if p, err := os.FindProcess(os.Getpid()); err == nil && p.Signal(sig) == nil {
+				return
+			}
+			os.Exit(1)
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(quit)
+	}
+}
+
+// shutdownTimeout calls Shutdown waiting at most timeout, 5 seconds by default.
+func (l *LokiLogger) shutdownTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := l.Shutdown(ctx); err != nil {
+		l.logf("error", "Error loki shutdown: %v", err)
+	}
+}
+
+// drain sends the collected logs and waits until all batches being sent are
+// done or ctx is done.
+func (l *LokiLogger) drain(ctx context.Context) error {
+	l.Flush()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for l.inflight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			l.abortPushes()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// shipping returns the context of the batches shipped from now on, done when a drain runs out of time.
+// It carries the values of the context the logger was created with.
+func (l *LokiLogger) shipping() context.Context {
+	l.abortMu.Lock()
+	defer l.abortMu.Unlock()
+	return l.aborting
+}
+
+// pushContext returns the context of a push attempt of a batch shipped with the parent context, done
+// after PushTimeout or with the parent.
+func (l *LokiLogger) pushContext(parent context.Context, cfg *Config) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, cmp.Or(cfg.PushTimeout, defaultPushTimeout))
+}
+
+// abortPushes cancels the pushes in progress. The pushes started afterwards are not affected, so that the
+// logger keeps working if the process does not exit.
+func (l *LokiLogger) abortPushes() {
+	l.abortMu.Lock()
+	abort := l.abort
+	l.aborting, l.abort = context.WithCancelCause(context.WithoutCancel(l.ctx))
+	l.abortMu.Unlock()
+
+	abort(errDrainTimeout)
+}
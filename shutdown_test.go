@@ -0,0 +1,125 @@
+package lokilogger
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClosePushesWrittenEntries(t *testing.T) {
+	sink := &testSink{}
+	l := newTestLogger(t, context.Background(), Config{Sink: sink})
+
+	for range 25 {
+		if _, err := l.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if _, entries := sink.counts(); entries != 25 {
+		t.Errorf("pushed %d entries, want 25", entries)
+	}
+	if _, err := l.Write([]byte("late")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after Close: %v, want ErrClosed", err)
+	}
+}
+
+func TestCloseRetriesFailedPushes(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		cfg  Config
+	}{
+		{"default", Config{}},
+		{"retry budget", Config{RetryBudget: 10}},
+		{"strict ordering", Config{StrictOrdering: true}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &testSink{push: func(ctx context.Context, attempt int) error {
+				if attempt == 1 {
+					return errors.New("connection refused")
+				}
+				return nil
+			}}
+			tt.cfg.Sink = sink
+			l := newTestLogger(t, context.Background(), tt.cfg)
+
+			l.Write([]byte("hello"))
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := l.Close(ctx); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			if attempts, entries := sink.counts(); attempts != 2 || entries != 1 {
+				t.Errorf("got %d attempts pushing %d entries, want 2 pushing 1", attempts, entries)
+			}
+		})
+	}
+}
+
+func TestCloseAbortsPushesOnTimeout(t *testing.T) {
+	aborted := make(chan error, 1)
+	sink := &testSink{push: func(ctx context.Context, attempt int) error {
+		<-ctx.Done()
+		aborted <- context.Cause(ctx)
+		return ctx.Err()
+	}}
+	l := newTestLogger(t, context.Background(), Config{Sink: sink, StrictOrdering: true})
+
+	l.Write([]byte("hello"))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close: %v, want context.DeadlineExceeded", err)
+	}
+
+	select {
+	case err := <-aborted:
+		if !errors.Is(err, errDrainTimeout) {
+			t.Errorf("push cancelled with %v, want errDrainTimeout", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("push not aborted")
+	}
+
+	// The aborted batch is not retried.
+	time.Sleep(1500 * time.Millisecond)
+	if attempts, _ := sink.counts(); attempts != 1 {
+		t.Errorf("got %d attempts, want 1", attempts)
+	}
+}
+
+func TestCancelPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		policy  CancelPolicy
+		pushed  int
+		dropped int64
+	}{
+		{CancelFlush, 10, 0},
+		{CancelDiscard, 0, 10},
+	} {
+		sink := &testSink{}
+		ctx, cancel := context.WithCancel(context.Background())
+		l := newTestLogger(t, ctx, Config{Sink: sink, CancelPolicy: tt.policy})
+
+		for range 10 {
+			l.Write([]byte("hello"))
+		}
+		cancel()
+		<-l.stopped
+
+		if _, entries := sink.counts(); entries != tt.pushed {
+			t.Errorf("CancelPolicy %d: pushed %d entries, want %d", tt.policy, entries, tt.pushed)
+		}
+		if dropped := l.Stats().Dropped; dropped != tt.dropped {
+			t.Errorf("CancelPolicy %d: dropped %d entries, want %d", tt.policy, dropped, tt.dropped)
+		}
+		if _, err := l.Write([]byte("late")); !errors.Is(err, ErrClosed) {
+			t.Errorf("CancelPolicy %d: Write after cancel: %v, want ErrClosed", tt.policy, err)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+package lokilogger
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSigV4Service = "execute-api"
+	sigV4Algorithm      = "AWS4-HMAC-SHA256"
+	// Credentials are refreshed this long before they expire, and static ones after credentialsTTL.
+	credentialsExpiryWindow = 5 * time.Minute
+	credentialsTTL          = time.Hour
+)
+
+// SigV4Config signs the requests with AWS Signature Version 4, e.g. for Loki
+// behind Amazon API Gateway with IAM authorization or Amazon Managed Grafana.
+type SigV4Config struct {
+	// Region of the endpoint, AWS_REGION or AWS_DEFAULT_REGION by default.
+	Region string
+	// Service signing name, "execute-api" by default.
+	Service string
+	// Profile of the shared credentials file, AWS_PROFILE or "default" by default.
+	Profile string
+	// Credentials overrides the default credential chain, e.g. with a provider of the AWS SDK.
+	// By default credentials are looked up in the environment (AWS_ACCESS_KEY_ID,
+	// AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN), the shared credentials file, the ECS
+	// container credentials endpoint and the EC2 instance metadata service, in that order.
+	Credentials func(ctx context.Context) (AWSCredentials, error)
+}
+
+// region returns the configured region or the one of the environment.
+func (c *SigV4Config) region() string {
+	return cmp.Or(c.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+}
+
+// AWSCredentials are the credentials signing requests with SigV4.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time // Zero if the credentials don't expire.
+}
+
+// sigV4Transport signs the requests before passing them to the next transport.
+type sigV4Transport struct {
+	next    http.RoundTripper
+	region  string
+	service string
+	creds   func(ctx context.Context) (AWSCredentials, error)
+	now     func() time.Time
+
+	mu     sync.Mutex
+	cached AWSCredentials
+	expiry time.Time
+}
+
+func newSigV4Transport(next http.RoundTripper, cfg *SigV4Config) *sigV4Transport {
+	t := &sigV4Transport{
+		next:    next,
+		region:  cfg.region(),
+		service: cmp.Or(cfg.Service, defaultSigV4Service),
+		creds:   cfg.Credentials,
+		now:     time.Now,
+	}
+
+	if t.creds == nil {
+		profile := cfg.Profile
+		t.creds = func(ctx context.Context) (AWSCredentials, error) {
+			return defaultAWSCredentials(ctx, profile)
+		}
+	}
+
+	return t
+}
+
+func (t *sigV4Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	creds, err := t.credentials(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("sigv4: %w", err)
+	}
+
+	payload, err := requestPayload(req)
+	if err != nil {
+		return nil, fmt.Errorf("sigv4: %w", err)
+	}
+
+	// The request must not be modified by a RoundTripper.
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	if payload == nil {
+		req.Body = nil
+	}
+
+	hash := sha256.Sum256(payload)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(hash[:]))
+	signV4(req, hex.EncodeToString(hash[:]), creds, t.region, t.service, t.now())
+
+	return t.next.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport.
+func (t *sigV4Transport) CloseIdleConnections() {
+	if c, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// credentials returns the cached credentials, refreshing them shortly before they expire.
+func (t *sigV4Transport) credentials(ctx context.Context) (AWSCredentials, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.cached.AccessKeyID != "" && t.now().Before(t.expiry) {
+		return t.cached, nil
+	}
+
+	creds, err := t.creds(ctx)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	t.cached, t.expiry = creds, t.now().Add(credentialsTTL)
+	if !creds.Expires.IsZero() {
+		t.expiry = creds.Expires.Add(-credentialsExpiryWindow)
+	}
+
+	return creds, nil
+}
+
+// requestPayload reads the body of the request, closing it.
+func requestPayload(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+
+	return io.ReadAll(req.Body)
+}
+
+// signV4 adds the X-Amz-Date, X-Amz-Security-Token and Authorization headers to the
+// request. The host, Content-Type and X-Amz-* headers are signed.
+func signV4(req *http.Request, payloadHash string, creds AWSCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + region + "/" + service + "/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.Join(values, ",")
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.Join(strings.Fields(headers[name]), " ") + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL),
+		canonicalQuery(req.URL),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := sigV4Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), now.Format("20060102"))
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalURI returns the path with every segment URI-encoded twice, as required by all services but S3.
+func canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = awsEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery returns the query parameters sorted by name and value and URI-encoded.
+func canonicalQuery(u *url.URL) string {
+	var params []string
+	for name, values := range u.Query() {
+		for _, v := range values {
+			params = append(params, awsEscape(name)+"="+awsEscape(v))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// awsEscape percent-encodes everything but the unreserved characters of RFC 3986.
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// defaultAWSCredentials looks up the credentials in the environment, the shared credentials
+// file, the ECS container credentials endpoint and the EC2 instance metadata service.
+func defaultAWSCredentials(ctx context.Context, profile string) (AWSCredentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return AWSCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+
+	if creds, err := sharedAWSCredentials(profile); err == nil {
+		return creds, nil
+	}
+
+	if creds, err := containerAWSCredentials(ctx); err == nil {
+		return creds, nil
+	}
+
+	if creds, err := instanceAWSCredentials(ctx); err == nil {
+		return creds, nil
+	}
+
+	return AWSCredentials{}, errors.New("no AWS credentials found in the environment, shared credentials file, container or instance metadata")
+}
+
+// sharedAWSCredentials reads the profile of the shared credentials file.
+func sharedAWSCredentials(profile string) (AWSCredentials, error) {
+	if profile == "" {
+		profile = os.Getenv("AWS_PROFILE")
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return AWSCredentials{}, err
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	defer f.Close()
+
+	var creds AWSCredentials
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if section != profile {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return AWSCredentials{}, err
+	}
+
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return AWSCredentials{}, fmt.Errorf("profile %q not found in %s", profile, path)
+	}
+	return creds, nil
+}
+
+// metadataCredentials is the credentials document of the container and instance metadata endpoints.
+type metadataCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+// containerAWSCredentials fetches the credentials of the ECS task role.
+func containerAWSCredentials(ctx context.Context) (AWSCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if rel := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); rel != "" {
+		endpoint = "http://169.254.170.2" + rel
+	}
+	if endpoint == "" {
+		return AWSCredentials{}, errors.New("no container credentials endpoint")
+	}
+
+	header := http.Header{}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		header.Set("Authorization", token)
+	}
+
+	return fetchMetadataCredentials(ctx, endpoint, header)
+}
+
+// instanceAWSCredentials fetches the credentials of the EC2 instance role with IMDSv2.
+func instanceAWSCredentials(ctx context.Context) (AWSCredentials, error) {
+	const imds = "http://169.254.169.254/latest"
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, imds+"/api/token", nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+
+	token, err := metadataGet(req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	header := http.Header{"X-Aws-Ec2-Metadata-Token": {string(token)}}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, imds+"/meta-data/iam/security-credentials/", nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header = header.Clone()
+
+	role, err := metadataGet(req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	name, _, _ := strings.Cut(strings.TrimSpace(string(role)), "\n")
+	return fetchMetadataCredentials(ctx, imds+"/meta-data/iam/security-credentials/"+name, header)
+}
+
+// fetchMetadataCredentials fetches a credentials document from a metadata endpoint.
+func fetchMetadataCredentials(ctx context.Context, endpoint string, header http.Header) (AWSCredentials, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header = header
+
+	body, err := metadataGet(req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+
+	var doc metadataCredentials
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return AWSCredentials{}, fmt.Errorf("decode credentials: %w", err)
+	}
+
+	return AWSCredentials{
+		AccessKeyID:     doc.AccessKeyID,
+		SecretAccessKey: doc.SecretAccessKey,
+		SessionToken:    doc.Token,
+		Expires:         doc.Expiration,
+	}, nil
+}
+
+// metadataGet performs a request to a metadata endpoint and returns the body of a 200 response.
+func metadataGet(req *http.Request) ([]byte, error) {
+	client := &http.Client{Timeout: 2 * time.Second}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status code %d", req.URL.Redacted(), resp.StatusCode)
+	}
+	return body, nil
+}
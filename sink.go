@@ -0,0 +1,273 @@
+package lokilogger
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Stream is a batch of entries sharing the same stream labels.
+type Stream struct {
+	Labels  map[string]string
+	Entries []Entry
+	// Tenant of the entries, sent as X-Scope-OrgID instead of the sink's tenant when set.
+	Tenant string `json:",omitempty"`
+
+	route int // 1-based index of the route of the entries, see Route.
+}
+
+// Sink is a destination for batches of log streams. Push is retried by the
+// logger according to Config.RetryCount unless the error is permanent.
+type Sink interface {
+	Push(ctx context.Context, streams []Stream) error
+}
+
+// errSinkPanic marks pushes failed by a panicking sink, which are not retried.
+var errSinkPanic = errors.New("sink panicked")
+
+// pushSafely pushes the streams to the sink, turning a panic of the sink into an error, so that a faulty
+// custom sink fails its batches instead of crashing the process from the sending goroutine.
+func pushSafely(ctx context.Context, sink Sink, streams []Stream) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %T: %v", errSinkPanic, sink, r)
+		}
+	}()
+	return sink.Push(ctx, streams)
+}
+
+// retryable reports whether a failed push may succeed when retried.
+func retryable(err error) bool {
+	if errors.Is(err, errSinkPanic) {
+		return false
+	}
+	var pe *PushError
+	if errors.As(err, &pe) {
+		return pe.StatusCode >= 500
+	}
+	return true
+}
+
+// checkResponse drains and closes the response body and converts non-2xx responses into a PushError
+// with at most maxErrorBody bytes of the body.
+func checkResponse(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody+1))
+	if err != nil {
+		return fmt.Errorf("unexpected status code %d: read body: %w", resp.StatusCode, err)
+	}
+	// The rest is drained, so that the connection can be reused.
+	io.Copy(io.Discard, resp.Body)
+
+	pe := &PushError{StatusCode: resp.StatusCode, TraceID: traceID(resp.Header), Body: string(body)}
+	if len(body) > maxErrorBody {
+		pe.Body, pe.Truncated = truncateLine(pe.Body, maxErrorBody), true
+	}
+	return pe
+}
+
+// LokiSink pushes streams to the Loki push API or, with ProtocolOTLP, to an OTLP/HTTP logs endpoint.
+type LokiSink struct {
+	URL         string   // Loki API server endpoint URL.
+	AccessToken string   // Authentication token for accessing the Loki API.
+	TenantID    string   // Tenant sent as the X-Scope-OrgID header.
+	Protocol    Protocol // Push format, ProtocolLoki by default.
+	Client      *http.Client
+	// ExpectContinue sends "Expect: 100-continue", delaying the upload of the payload until the server
+	// accepts the request. The transport of the client needs an ExpectContinueTimeout.
+	ExpectContinue bool
+}
+
+// Push implements Sink. Streams of different tenants are pushed in separate requests.
+func (s *LokiSink) Push(ctx context.Context, streams []Stream) error {
+	for _, group := range splitTenants(streams) {
+		if err := s.push(ctx, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// push sends streams of a single tenant in one request.
+func (s *LokiSink) push(ctx context.Context, streams []Stream) error {
+	var body io.ReadCloser
+	var size int
+	contentType := "application/json"
+
+	if s.Protocol == ProtocolOTLP {
+		data := encodeOTLP(streams)
+		body, size, contentType = io.NopCloser(bytes.NewReader(data)), len(data), "application/x-protobuf"
+	} else {
+		// The payload is encoded straight into a pooled buffer, released once the transport is done with it.
+		buf := bufPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		encodeLokiJSON(buf, streams)
+		body, size = newPooledBody(buf), buf.Len()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.URL, body)
+	if err != nil {
+		body.Close()
+		return err
+	}
+
+	req.ContentLength = int64(size)
+	req.Header.Set("Content-Type", contentType)
+
+	if s.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.AccessToken)
+	}
+
+	if tenant := cmp.Or(streamsTenant(streams), s.TenantID); tenant != "" {
+		req.Header.Set("X-Scope-OrgID", tenant)
+	}
+
+	if s.ExpectContinue {
+		req.Header.Set("Expect", "100-continue")
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	return checkResponse(resp)
+}
+
+// jsonRecord is the representation of an entry written by the JSON based sinks.
+type jsonRecord struct {
+	Time     time.Time         `json:"time"`
+	Level    string            `json:"level"`
+	Line     string            `json:"line"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Tenant   string            `json:"tenant,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// toJSONRecords flattens the streams into records.
+func toJSONRecords(streams []Stream) []jsonRecord {
+	var records []jsonRecord
+	for _, s := range streams {
+		for _, e := range s.Entries {
+			records = append(records, jsonRecord{
+				Time:     e.Time,
+				Level:    e.Level,
+				Line:     e.Line,
+				Labels:   s.Labels,
+				Tenant:   s.Tenant,
+				Metadata: e.Metadata,
+			})
+		}
+	}
+	return records
+}
+
+// JSONSink writes every entry as a JSON object on its own line (NDJSON) to a writer.
+type JSONSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONSink returns a Sink writing NDJSON to w.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+// NewStdoutSink returns a Sink writing NDJSON to stdout.
+func NewStdoutSink() *JSONSink {
+	return NewJSONSink(os.Stdout)
+}
+
+// Push implements Sink.
+func (s *JSONSink) Push(ctx context.Context, streams []Stream) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range toJSONRecords(streams) {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+// FileSink appends entries as NDJSON to a local file.
+type FileSink struct {
+	*JSONSink
+	f *os.File
+}
+
+// NewFileSink opens or creates the file at path and returns a Sink appending NDJSON to it.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{JSONSink: NewJSONSink(f), f: f}, nil
+}
+
+// Close closes the underlying file.
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+// WebhookSink posts entries as a JSON array to a generic HTTP endpoint.
+type WebhookSink struct {
+	URL     string
+	Headers map[string]string // Extra request headers, e.g. authentication.
+	Client  *http.Client
+}
+
+// Push implements Sink.
+func (s *WebhookSink) Push(ctx context.Context, streams []Stream) error {
+	body, err := json.Marshal(toJSONRecords(streams))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	return checkResponse(resp)
+}
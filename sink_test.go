@@ -0,0 +1,78 @@
+package lokilogger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// roundTripFunc is an http.RoundTripper calling the function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// TestPushRetriesTransportErrors checks that pushes failing without a response are retried and reported
+// instead of dereferencing the missing response.
+func TestPushRetriesTransportErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		failures int
+		attempts int
+		final    bool
+	}{
+		{"recovers", 1, 2, false},
+		{"gives up", 2, 2, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var attempts int
+			var failed []FailedPush
+			cfg := Config{
+				RetryCount: 2,
+				OnError: func(f FailedPush) {
+					mu.Lock()
+					failed = append(failed, f)
+					mu.Unlock()
+				},
+			}
+			l := newTestLogger(t, context.Background(), cfg)
+			l.client.Transport = &debugTransport{l: l, next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				attempts++
+				n := attempts
+				mu.Unlock()
+
+				if n <= tt.failures {
+					return nil, errors.New("connection reset by peer")
+				}
+				return &http.Response{StatusCode: http.StatusNoContent, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+			})}
+
+			l.Write([]byte("hello"))
+			if err := l.Shutdown(context.Background()); err != nil {
+				t.Fatalf("Shutdown: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if attempts != tt.attempts {
+				t.Errorf("got %d attempts, want %d", attempts, tt.attempts)
+			}
+			if len(failed) != tt.failures {
+				t.Fatalf("OnError called %d times, want %d", len(failed), tt.failures)
+			}
+			if last := failed[len(failed)-1]; last.Final != tt.final || last.Err == nil {
+				t.Errorf("last failure final %t with %v, want final %t with an error", last.Final, last.Err, tt.final)
+			}
+
+			stats := l.Stats()
+			if want := int64(2 - tt.failures); stats.Sent != want {
+				t.Errorf("sent %d entries, want %d", stats.Sent, want)
+			}
+		})
+	}
+}
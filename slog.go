@@ -0,0 +1,109 @@
+package lokilogger
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+)
+
+// Handler is a slog.Handler shipping the records through the logger like LogCtx. The attributes and
+// groups added with WithAttrs and WithGroup are flattened according to Config.AttrRules once, when the
+// handler is derived, so that a record only copies them and flattens its own attributes. Changes of
+// AttrRules by UpdateConfig apply to handlers derived afterwards.
+//
+//	slog.SetDefault(slog.New(l.Handler()))
+type Handler struct {
+	l       *LokiLogger
+	labels  map[string]string // Labels of the child logger and the attributes promoted by AttrRules.
+	attrs   map[string]string // Flattened attributes of WithAttrs.
+	prefix  string            // Groups of WithGroup joined with the separator, ending with it.
+	dropped bool              // Whether a group of WithGroup is left out by AttrRules.Drop.
+}
+
+// Handler returns a slog.Handler shipping the records through the logger.
+func (l *LokiLogger) Handler() *Handler {
+	return &Handler{l: l}
+}
+
+// Handler returns a slog.Handler shipping the records with the labels of the child.
+func (c *Child) Handler() *Handler {
+	return &Handler{l: c.l, labels: c.labels}
+}
+
+// Enabled implements slog.Handler, reporting whether the level passes Config.MinLevel.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return levelEnabled(h.l.config().MinLevel, SlogLevel(level))
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	select {
+	case <-h.l.ctx.Done():
+		return ErrClosed
+	default:
+	}
+
+	cfg := h.l.config()
+	e := Entry{
+		Time:     r.Time,
+		Level:    SlogLevel(r.Level),
+		Line:     h.l.redact(r.Message),
+		Labels:   h.labels,
+		Metadata: make(map[string]string, len(h.attrs)+r.NumAttrs()+2),
+	}
+	if e.Time.IsZero() {
+		e.Time = cfg.Clock.Now()
+	}
+
+	maps.Copy(e.Metadata, h.attrs)
+	if !h.dropped {
+		r.Attrs(func(a slog.Attr) bool {
+			addAttr(&e, &cfg.AttrRules, h.prefix, a)
+			return true
+		})
+	}
+
+	if cfg.Caller && r.PC != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		e.Metadata[callerKey] = filepath.Base(f.File) + ":" + strconv.Itoa(f.Line)
+	}
+	h.l.logEntry(ctx, cfg, e)
+
+	return nil
+}
+
+// WithAttrs implements slog.Handler, flattening the attributes once for all records of the handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 || h.dropped {
+		return h
+	}
+
+	e := Entry{Labels: h.labels, Metadata: make(map[string]string, len(h.attrs)+len(attrs))}
+	maps.Copy(e.Metadata, h.attrs)
+	rules := &h.l.config().AttrRules
+	for _, a := range attrs {
+		addAttr(&e, rules, h.prefix, a)
+	}
+
+	c := *h
+	c.labels, c.attrs = e.Labels, e.Metadata
+	return &c
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" || h.dropped {
+		return h
+	}
+
+	rules := &h.l.config().AttrRules
+	c := *h
+	c.dropped = slices.Contains(rules.Drop, h.prefix+name)
+	c.prefix += name + cmp.Or(rules.Separator, ".")
+	return &c
+}
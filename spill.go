@@ -0,0 +1,234 @@
+package lokilogger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSpillMaxBytes = 100 << 20
+	defaultSpillMaxAge   = 24 * time.Hour
+)
+
+// spool stores batches that can't be sent or held in memory as files in a
+// directory, one JSON encoded batch per file, and replays them in order.
+type spool struct {
+	dir string
+
+	mu        sync.Mutex
+	seq       int64
+	pending   atomic.Bool // Whether the directory may contain batches to replay.
+	replaying atomic.Bool
+}
+
+// newSpool creates the spill directory.
+func newSpool(dir string) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("spill dir: %w", err)
+	}
+	s := &spool{dir: dir}
+	// Batches left by a previous run are replayed as well.
+	s.pending.Store(true)
+	return s, nil
+}
+
+// write stores the streams in a new file and removes the oldest files
+// exceeding maxBytes or maxAge.
+func (s *spool) write(streams []Stream, maxBytes int64, maxAge time.Duration) ([]string, error) {
+	data, err := json.Marshal(streams)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d.json", time.Now().UnixNano(), s.seq)
+
+	// Write to a temporary file first so that replay never reads a partial batch.
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	s.pending.Store(true)
+
+	return s.rotate(maxBytes, maxAge)
+}
+
+// rotate removes files older than maxAge and the oldest files while the
+// directory exceeds maxBytes. It returns the removed files.
+func (s *spool) rotate(maxBytes int64, maxAge time.Duration) ([]string, error) {
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]os.FileInfo, 0, len(files))
+	var total int64
+	for _, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, fi)
+		total += fi.Size()
+	}
+
+	var removed []string
+	for i, fi := range infos {
+		if total <= maxBytes && time.Since(fi.ModTime()) <= maxAge {
+			break
+		}
+		if os.Remove(files[i]) == nil {
+			removed = append(removed, files[i])
+			total -= fi.Size()
+		}
+	}
+
+	return removed, nil
+}
+
+// files returns the spilled batches, oldest first.
+func (s *spool) files() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			files = append(files, filepath.Join(s.dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// replay pushes the spilled batches to the sink in order, removing each
+// file once sent. The streams are passed through prepare before each push.
+// Batches failing permanently are handed to reject and removed, while the
+// replay stops at the first retryable failure, leaving the remaining files
+// for the next replay. It returns the number of entries sent.
+func (s *spool) replay(ctx context.Context, sink Sink, prepare func([]Stream) []Stream, reject func([]Stream, error)) (int, error) {
+	if !s.pending.Load() || !s.replaying.CompareAndSwap(false, true) {
+		return 0, nil
+	}
+	defer s.replaying.Store(false)
+
+	// Batches spilled during the replay set pending again.
+	s.pending.Store(false)
+
+	files, err := s.files()
+	if err != nil {
+		s.pending.Store(true)
+		return 0, err
+	}
+
+	sent := 0
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			s.pending.Store(true)
+			return sent, err
+		}
+
+		var streams []Stream
+		if err := json.Unmarshal(data, &streams); err != nil {
+			// A corrupt file would block the replay forever.
+			os.Remove(f)
+			continue
+		}
+
+		streams = prepare(streams)
+		if err := classify(pushSafely(ctx, sink, streams)); err != nil {
+			if retryable(err) {
+				s.pending.Store(true)
+				return sent, err
+			}
+			// Like a corrupt file, a rejected batch would block the replay forever.
+			reject(streams, err)
+			os.Remove(f)
+			continue
+		}
+
+		os.Remove(f)
+		sent += countEntries(streams)
+	}
+
+	return sent, nil
+}
+
+// spill writes the streams to the spill directory, reporting whether they were stored.
+func (l *LokiLogger) spill(streams []Stream) bool {
+	if l.spool == nil || len(streams) == 0 {
+		return false
+	}
+
+	cfg := l.config()
+	maxBytes, maxAge := cfg.SpillMaxBytes, cfg.SpillMaxAge
+	if maxBytes <= 0 {
+		maxBytes = defaultSpillMaxBytes
+	}
+	if maxAge <= 0 {
+		maxAge = defaultSpillMaxAge
+	}
+
+	removed, err := l.spool.write(streams, maxBytes, maxAge)
+	if err != nil {
+		l.logf("error", "Error loki spill to %s: %v", l.spool.dir, err)
+		return false
+	}
+	if len(removed) > 0 {
+		l.logf("warn", "Removed %d spilled batches exceeding SpillMaxBytes or SpillMaxAge", len(removed))
+	}
+
+	return true
+}
+
+// replaySpill sends the spilled batches to the sink once it accepts pushes again.
+func (l *LokiLogger) replaySpill(sink Sink) {
+	if l.spool == nil {
+		return
+	}
+
+	cfg := l.config()
+	prepare := func(streams []Stream) []Stream {
+		// The spilled entries are likely older than MaxTimestampAge after an outage.
+		if cfg.MaxTimestampAge > 0 {
+			streams = clampTimestamps(streams, cfg.Clock.Now().Add(-cfg.MaxTimestampAge))
+		}
+		return streams
+	}
+	reject := func(streams []Stream, err error) {
+		l.counters.failure(err)
+		l.counters.failed.Add(int64(countEntries(streams)))
+		if l.deadLetter(streams, err) {
+			l.logf("error", "Error loki replay to %T, spilled batch written to %s: %v", sink, cfg.DeadLetterFile, err)
+			return
+		}
+		l.logf("error", "Error loki replay to %T, dropping spilled batch: %v", sink, err)
+	}
+
+	sent, err := l.spool.replay(l.ctx, sink, prepare, reject)
+	if sent > 0 {
+		l.counters.success(sent)
+	}
+	if err != nil {
+		l.logf("warn", "Error loki replay of spilled batches: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package lokilogger
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSpoolReplay(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		err      error
+		sent     int
+		rejected int
+		left     int
+	}{
+		{"permanent failure", &PushError{StatusCode: http.StatusBadRequest}, 2, 1, 0},
+		{"retryable failure", &PushError{StatusCode: http.StatusServiceUnavailable}, 1, 0, 2},
+		{"sink panic", errSinkPanic, 2, 1, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newSpool(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for range 3 {
+				streams := []Stream{{Labels: map[string]string{"app": "test"}, Entries: []Entry{{Time: time.Now(), Line: "hello"}}}}
+				if _, err := s.write(streams, defaultSpillMaxBytes, defaultSpillMaxAge); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The second batch fails.
+			sink := &testSink{push: func(ctx context.Context, attempt int) error {
+				if attempt == 2 {
+					return tt.err
+				}
+				return nil
+			}}
+			rejected := 0
+			sent, err := s.replay(context.Background(), sink,
+				func(streams []Stream) []Stream { return streams },
+				func(streams []Stream, err error) { rejected++ })
+
+			if sent != tt.sent || rejected != tt.rejected {
+				t.Errorf("sent %d entries and rejected %d batches, want %d and %d", sent, rejected, tt.sent, tt.rejected)
+			}
+			if retryable(tt.err) != (err != nil) {
+				t.Errorf("replay: %v", err)
+			}
+			if files, _ := s.files(); len(files) != tt.left {
+				t.Errorf("%d files left, want %d", len(files), tt.left)
+			}
+		})
+	}
+}
+
+func TestReplaySpillClampsTimestamps(t *testing.T) {
+	withoutStdout(t)
+
+	sink := entrySink{entries: make(chan Entry, 1)}
+	l := newTestLogger(t, context.Background(), Config{Sink: nopSink{}, SpillDir: t.TempDir(), MaxTimestampAge: time.Hour})
+	defer l.Close(context.Background())
+
+	old := time.Now().Add(-3 * time.Hour)
+	if !l.spill([]Stream{{Labels: map[string]string{"app": "test"}, Entries: []Entry{{Time: old, Line: "hello"}}}}) {
+		t.Fatal("batch not spilled")
+	}
+	l.replaySpill(sink)
+
+	select {
+	case e := <-sink.entries:
+		if time.Since(e.Time) > time.Hour+time.Minute || e.Metadata[originalTimeKey] != old.UTC().Format(time.RFC3339Nano) {
+			t.Errorf("replayed %+v", e)
+		}
+	default:
+		t.Fatal("spilled batch not replayed")
+	}
+	if failed := l.Stats().Failed; failed != 0 {
+		t.Errorf("%d entries failed, want 0", failed)
+	}
+}
@@ -0,0 +1,102 @@
+package lokilogger
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// SplunkSink pushes entries to the Splunk HTTP Event Collector. Every entry becomes an event with the
+// line as event, the stream labels and structured metadata as indexed fields, the host label as host
+// and the service_name label as source.
+type SplunkSink struct {
+	URL   string // Event endpoint, e.g. https://splunk:8088/services/collector/event.
+	Token string // HEC token, sent as "Authorization: Splunk <token>".
+	// Index and SourceType of the events, or the values of the IndexLabel and SourceTypeLabel stream
+	// labels when set, e.g. IndexLabel "team" sends the logs of every team to the index named after it.
+	// Events without them go to the default index and source type of the token.
+	Index           string
+	SourceType      string
+	IndexLabel      string
+	SourceTypeLabel string
+	Client          *http.Client
+}
+
+// Push implements Sink. The events of all streams are sent in one request.
+func (s *SplunkSink) Push(ctx context.Context, streams []Stream) error {
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	s.encode(buf, streams)
+	size := buf.Len()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.URL, newPooledBody(buf))
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = int64(size)
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Splunk "+s.Token)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	return checkResponse(resp)
+}
+
+// encode writes the entries as concatenated HEC events:
+//
+//	{"time":1704207845.123,"host":"web-1","source":"api","sourcetype":"json","index":"main","event":"line","fields":{"level":"info"}}
+func (s *SplunkSink) encode(buf *bytes.Buffer, streams []Stream) {
+	for _, st := range streams {
+		index := cmp.Or(st.Labels[s.IndexLabel], s.Index)
+		sourceType := cmp.Or(st.Labels[s.SourceTypeLabel], s.SourceType)
+
+		for _, e := range st.Entries {
+			buf.WriteString(`{"time":`)
+			buf.Write(strconv.AppendFloat(buf.AvailableBuffer(), float64(e.Time.UnixMicro())/1e6, 'f', -1, 64))
+			writeJSONField(buf, "host", st.Labels["host"])
+			writeJSONField(buf, "source", st.Labels["service_name"])
+			writeJSONField(buf, "sourcetype", sourceType)
+			writeJSONField(buf, "index", index)
+			buf.WriteString(`,"event":`)
+			writeJSONString(buf, e.Line)
+
+			fields := st.Labels
+			if len(e.Metadata) > 0 {
+				fields = make(map[string]string, len(st.Labels)+len(e.Metadata))
+				for k, v := range e.Metadata {
+					fields[k] = v
+				}
+				for k, v := range st.Labels {
+					fields[k] = v
+				}
+			}
+			buf.WriteString(`,"fields":`)
+			writeJSONObject(buf, fields)
+			buf.WriteString("}\n")
+		}
+	}
+}
+
+// writeJSONField writes `,"key":"value"` unless the value is empty.
+func writeJSONField(buf *bytes.Buffer, key, value string) {
+	if value == "" {
+		return
+	}
+	buf.WriteByte(',')
+	writeJSONString(buf, key)
+	buf.WriteByte(':')
+	writeJSONString(buf, value)
+}
@@ -0,0 +1,101 @@
+package lokilogger
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// DB wraps a *sql.DB logging failed queries at error level and queries slower
+// than its threshold at warn level, with the query, duration and affected rows
+// as structured metadata in the stream labeled log_type=sql. Methods not
+// overridden, e.g. transactions, are passed through unlogged.
+type DB struct {
+	*sql.DB
+	l    *LokiLogger
+	slow time.Duration
+}
+
+// WrapDB returns db logging failed queries and queries slower than slow. Slow queries aren't logged when slow is zero.
+func (l *LokiLogger) WrapDB(db *sql.DB, slow time.Duration) *DB {
+	return &DB{DB: db, l: l, slow: slow}
+}
+
+// ExecContext executes the query like sql.DB.ExecContext and logs it.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	res, err := db.DB.ExecContext(ctx, query, args...)
+
+	rows := int64(-1)
+	if err == nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			rows = n
+		}
+	}
+	db.l.logQuery(ctx, query, rows, start, db.slow, err)
+
+	return res, err
+}
+
+// Exec executes the query like sql.DB.Exec and logs it.
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// QueryContext executes the query like sql.DB.QueryContext and logs it.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	db.l.logQuery(ctx, query, -1, start, db.slow, err)
+
+	return rows, err
+}
+
+// Query executes the query like sql.DB.Query and logs it.
+func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryRowContext executes the query like sql.DB.QueryRowContext and logs it
+// when slow. Errors are deferred to Row.Scan and therefore not logged.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	db.l.logQuery(ctx, query, -1, start, db.slow, nil)
+
+	return row
+}
+
+// QueryRow executes the query like sql.DB.QueryRow and logs it when slow.
+func (db *DB) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// logQuery logs a failed query at error level and a query slower than slow at
+// warn level. Unknown affected rows are passed as -1.
+func (l *LokiLogger) logQuery(ctx context.Context, query string, rows int64, start time.Time, slow time.Duration, err error) {
+	duration := time.Since(start)
+
+	var level, msg string
+	switch {
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		level, msg = "error", "query failed: "+err.Error()
+	case slow > 0 && duration >= slow:
+		level, msg = "warn", "slow query: "+duration.String()
+	default:
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("sql", query),
+		slog.String("duration", duration.String()),
+	}
+	if rows >= 0 {
+		attrs = append(attrs, slog.String("rows", strconv.FormatInt(rows, 10)))
+	}
+
+	l.logCtx(ctx, map[string]string{"log_type": "sql"}, level, msg, attrs...)
+}
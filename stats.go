@@ -0,0 +1,173 @@
+package lokilogger
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the logger's shipping counters.
+type Stats struct {
+	Received        int64     // Entries written to the logger.
+	Sent            int64     // Entries pushed successfully, counted per sink.
+	Dropped         int64     // Entries sampled away, rate limited or evicted from a full buffer.
+	Failed          int64     // Entries that could not be delivered after all retries, counted per sink.
+	Retried         int64     // Push attempts that were retried.
+	QueueLen        int       // Entries waiting in the batch or held until Loki becomes ready.
+	LastError       error     // Error of the most recent failed push attempt.
+	LastErrorTime   time.Time // Time of the most recent failed push attempt.
+	LastSuccessTime time.Time // Time of the most recent successful push.
+	// Failed push attempts by cause: ErrRateLimited, ErrUnauthorized, ErrUnavailable, ErrEntryTooOld and ErrLineTooLong.
+	RateLimited  int64
+	Unauthorized int64
+	Unavailable  int64
+	EntryTooOld  int64
+	LineTooLong  int64
+
+	// SchemaViolations counts the entries violating Config.Schema, whatever its Policy.
+	SchemaViolations int64
+}
+
+// counters holds the internal counters published by Stats.
+type counters struct {
+	received atomic.Int64
+	sent     atomic.Int64
+	dropped  atomic.Int64
+	failed   atomic.Int64
+	retried  atomic.Int64
+	schema   atomic.Int64
+	causes   [len(errorCauses)]atomic.Int64 // Failed push attempts by cause.
+
+	mu            sync.Mutex
+	lastError     error
+	lastErrorTime time.Time
+	lastSuccess   time.Time
+}
+
+func (c *counters) success(n int) {
+	c.sent.Add(int64(n))
+
+	c.mu.Lock()
+	c.lastSuccess = time.Now()
+	c.mu.Unlock()
+}
+
+// errorCauses are the causes of failed pushes counted in Stats.
+var errorCauses = [...]error{ErrRateLimited, ErrUnauthorized, ErrUnavailable, ErrEntryTooOld, ErrLineTooLong}
+
+func (c *counters) failure(err error) {
+	for i, cause := range errorCauses {
+		if errors.Is(err, cause) {
+			c.causes[i].Add(1)
+		}
+	}
+
+	c.mu.Lock()
+	c.lastError = err
+	c.lastErrorTime = time.Now()
+	c.mu.Unlock()
+}
+
+// countEntries returns the number of entries in the streams.
+func countEntries(streams []Stream) int {
+	n := 0
+	for _, s := range streams {
+		n += len(s.Entries)
+	}
+	return n
+}
+
+// Stats returns a snapshot of the shipping counters.
+func (l *LokiLogger) Stats() Stats {
+	l.mu.Lock()
+	queued := len(l.logs) + l.bucketed + l.queue.len()
+	l.mu.Unlock()
+
+	queued += l.heldLen()
+
+	c := &l.counters
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Stats{
+		Received:        c.received.Load(),
+		Sent:            c.sent.Load(),
+		Dropped:         c.dropped.Load(),
+		Failed:          c.failed.Load(),
+		Retried:         c.retried.Load(),
+		QueueLen:        queued,
+		RateLimited:     c.causes[0].Load(),
+		Unauthorized:    c.causes[1].Load(),
+		Unavailable:     c.causes[2].Load(),
+		EntryTooOld:     c.causes[3].Load(),
+		LineTooLong:     c.causes[4].Load(),
+		LastError:       c.lastError,
+		LastErrorTime:   c.lastErrorTime,
+		LastSuccessTime: c.lastSuccess,
+
+		SchemaViolations: c.schema.Load(),
+	}
+}
+
+// Healthy reports whether logs are being shipped: Loki is ready and the most
+// recent push attempt succeeded, or none has failed yet.
+func (l *LokiLogger) Healthy() bool {
+	if !l.ready.Load() {
+		return false
+	}
+
+	c := &l.counters
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lastError == nil || !c.lastSuccess.Before(c.lastErrorTime)
+}
+
+// publishExpvar publishes the shipping counters as expvar variables named
+// <prefix>_received, <prefix>_sent, etc.
+func (l *LokiLogger) publishExpvar(prefix string) error {
+	vars := map[string]func(Stats) any{
+		"received":          func(s Stats) any { return s.Received },
+		"sent":              func(s Stats) any { return s.Sent },
+		"dropped":           func(s Stats) any { return s.Dropped },
+		"failed":            func(s Stats) any { return s.Failed },
+		"retried":           func(s Stats) any { return s.Retried },
+		"queue_len":         func(s Stats) any { return s.QueueLen },
+		"rate_limited":      func(s Stats) any { return s.RateLimited },
+		"unauthorized":      func(s Stats) any { return s.Unauthorized },
+		"unavailable":       func(s Stats) any { return s.Unavailable },
+		"entry_too_old":     func(s Stats) any { return s.EntryTooOld },
+		"line_too_long":     func(s Stats) any { return s.LineTooLong },
+		"schema_violations": func(s Stats) any { return s.SchemaViolations },
+		"last_success_time": func(s Stats) any { return formatTime(s.LastSuccessTime) },
+		"last_error": func(s Stats) any {
+			if s.LastError == nil {
+				return ""
+			}
+			return s.LastError.Error()
+		},
+	}
+
+	for name := range vars {
+		if expvar.Get(prefix+"_"+name) != nil {
+			return fmt.Errorf("expvar %q is already published", prefix+"_"+name)
+		}
+	}
+
+	for name, fn := range vars {
+		expvar.Publish(prefix+"_"+name, expvar.Func(func() any { return fn(l.Stats()) }))
+	}
+
+	return nil
+}
+
+// formatTime formats t as RFC 3339, or returns an empty string for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
@@ -0,0 +1,47 @@
+package lokilogger
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// StdLogger returns a *log.Logger shipping every message at level with the
+// extra stream labels, e.g. for http.Server.ErrorLog, whose TLS handshake and
+// panic messages otherwise only go to stderr:
+//
+//	srv := &http.Server{ErrorLog: l.StdLogger("warn", map[string]string{"component": "http"})}
+func (l *LokiLogger) StdLogger(level string, labels map[string]string) *log.Logger {
+	return log.New(&levelWriter{l: l, level: strings.ToLower(level), labels: mergeLabels(nil, labels)}, "", 0)
+}
+
+// StdLogger returns a *log.Logger like LokiLogger.StdLogger with the labels added to the labels of the child.
+func (c *Child) StdLogger(level string, labels map[string]string) *log.Logger {
+	return log.New(&levelWriter{l: c.l, level: strings.ToLower(level), labels: mergeLabels(c.labels, labels)}, "", 0)
+}
+
+// levelWriter ships every write as an entry at a fixed level.
+type levelWriter struct {
+	l      *LokiLogger
+	level  string
+	labels map[string]string
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	select {
+	case <-w.l.ctx.Done():
+		return 0, ErrClosed
+	default:
+	}
+
+	e := Entry{
+		Time:   time.Now(),
+		Level:  w.level,
+		Line:   w.l.redact(strings.TrimSpace(string(p))),
+		Labels: w.labels,
+	}
+	w.l.ship(e)
+	w.l.print(e)
+
+	return len(p), nil
+}
@@ -0,0 +1,47 @@
+package lokilogger
+
+import (
+	"sync"
+	"time"
+)
+
+// maxStrictBackoff caps the wait between the attempts of a batch retried until it succeeds with StrictOrdering.
+const maxStrictBackoff = 30 * time.Second
+
+// sequencer runs the sends of batches one after another from a single
+// goroutine with StrictOrdering. A batch being retried blocks the following
+// ones, which are buffered in the meantime.
+type sequencer struct {
+	mu      sync.Mutex
+	sends   []func()
+	running bool
+}
+
+// run queues send behind the pending sends and starts the sending goroutine unless it is running.
+func (s *sequencer) run(send func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sends = append(s.sends, send)
+	if s.running {
+		return
+	}
+	s.running = true
+
+	go func() {
+		for {
+			s.mu.Lock()
+			if len(s.sends) == 0 {
+				s.running = false
+				s.mu.Unlock()
+				return
+			}
+			send := s.sends[0]
+			s.sends[0] = nil
+			s.sends = s.sends[1:]
+			s.mu.Unlock()
+
+			send()
+		}
+	}()
+}
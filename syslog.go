@@ -0,0 +1,193 @@
+package lokilogger
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syslogSDID is the enterprise number of the structured data elements, the one reserved for documentation by RFC 5612.
+const syslogSDID = "@32473"
+
+// Syslog severities of the levels.
+var syslogSeverity = map[string]int{
+	"trace":    7,
+	"debug":    7,
+	"info":     6,
+	"warn":     4,
+	"error":    3,
+	"critical": 2,
+	"fatal":    1,
+	"panic":    0,
+}
+
+// SyslogSink forwards entries as RFC 5424 messages over TCP, or TLS as in RFC 5425, with octet-counting
+// framing. The level selects the severity, the host, service_name and pid labels the HOSTNAME, APP-NAME and
+// PROCID fields, and the other stream labels and the structured metadata become the labels and metadata
+// structured data elements:
+//
+//	<12>1 2024-01-02T15:04:05.123456Z web-1 api 42 - [labels@32473 env="prod"][metadata@32473 user="bob"] line
+//
+// The connection is opened on the first push and reopened after a failure.
+type SyslogSink struct {
+	Addr      string      // Address of the syslog server, e.g. syslog:6514.
+	TLSConfig *tls.Config // Connects with TLS when set.
+	Facility  int         // Facility of the messages, 1 (user) by default.
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Push implements Sink. The messages of a batch are written at once.
+func (s *SyslogSink) Push(ctx context.Context, streams []Stream) error {
+	var buf bytes.Buffer
+	for _, st := range streams {
+		for _, e := range st.Entries {
+			s.encode(&buf, st.Labels, e)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	} else {
+		s.conn.SetWriteDeadline(time.Time{})
+	}
+
+	if _, err := s.conn.Write(buf.Bytes()); err != nil {
+		// The server may have received part of the batch, but the connection can't be used anymore.
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection to the syslog server.
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *SyslogSink) dial(ctx context.Context) (net.Conn, error) {
+	if s.TLSConfig != nil {
+		d := tls.Dialer{Config: s.TLSConfig}
+		return d.DialContext(ctx, "tcp", s.Addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", s.Addr)
+}
+
+// encode writes the entry as an octet-counted RFC 5424 message.
+func (s *SyslogSink) encode(buf *bytes.Buffer, labels map[string]string, e Entry) {
+	var msg bytes.Buffer
+
+	severity, ok := syslogSeverity[e.Level]
+	if !ok {
+		severity = syslogSeverity["info"]
+	}
+	msg.WriteByte('<')
+	msg.WriteString(strconv.Itoa(cmp.Or(s.Facility, 1)*8 + severity))
+	msg.WriteString(">1 ")
+	msg.Write(e.Time.UTC().AppendFormat(msg.AvailableBuffer(), "2006-01-02T15:04:05.000000Z"))
+	msg.WriteByte(' ')
+	msg.WriteString(syslogField(cmp.Or(labels["host"], hostname()), 255))
+	msg.WriteByte(' ')
+	msg.WriteString(syslogField(labels["service_name"], 48))
+	msg.WriteByte(' ')
+	msg.WriteString(syslogField(labels["pid"], 128))
+	msg.WriteString(" - ")
+
+	sd := false
+	for _, el := range []struct {
+		id     string
+		params map[string]string
+	}{{"labels", labels}, {"metadata", e.Metadata}} {
+		written := false
+		for _, k := range sortedKeys(el.params) {
+			if el.id == "labels" && (k == "host" || k == "service_name" || k == "pid" || k == "level") {
+				continue
+			}
+			if !written {
+				msg.WriteString("[" + el.id + syslogSDID)
+				written, sd = true, true
+			}
+			msg.WriteByte(' ')
+			msg.WriteString(syslogParamName(k))
+			msg.WriteString(`="`)
+			syslogParamValue(&msg, el.params[k])
+			msg.WriteByte('"')
+		}
+		if written {
+			msg.WriteByte(']')
+		}
+	}
+	if !sd {
+		msg.WriteByte('-')
+	}
+
+	msg.WriteByte(' ')
+	msg.WriteString(e.Line)
+
+	buf.WriteString(strconv.Itoa(msg.Len()))
+	buf.WriteByte(' ')
+	buf.Write(msg.Bytes())
+}
+
+var hostname = sync.OnceValue(func() string {
+	h, _ := os.Hostname()
+	return h
+})
+
+// syslogField returns the header field truncated to n printable ASCII characters, or "-" if empty.
+func syslogField(v string, n int) string {
+	v = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, v)
+	if v == "" {
+		return "-"
+	}
+	return v[:min(len(v), n)]
+}
+
+// syslogParamName returns the label name as a structured data parameter name, which can't contain '=', ']' or '"'.
+func syslogParamName(k string) string {
+	return syslogField(strings.NewReplacer("=", "_", "]", "_", `"`, "_").Replace(k), 32)
+}
+
+// syslogParamValue writes the structured data parameter value, escaping '"', '\' and ']'.
+func syslogParamValue(buf *bytes.Buffer, v string) {
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c == '"' || c == '\\' || c == ']' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(v[i])
+	}
+}
@@ -0,0 +1,79 @@
+package lokilogger
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// tailResponse is a message of the Loki tail websocket.
+type tailResponse struct {
+	Streams []struct {
+		Stream map[string]string   `json:"stream"`
+		Values [][]json.RawMessage `json:"values"`
+	} `json:"streams"`
+}
+
+// Tail subscribes to the entries matching the LogQL query in near real time
+// using the Loki websocket tail endpoint. Every entry carries its stream
+// labels in Entry.Labels. The channel is closed when ctx is done or the
+// connection is lost.
+func (c *Client) Tail(ctx context.Context, logql string) (<-chan Entry, error) {
+	req, err := c.newRequest(ctx, "GET", "/loki/api/v1/tail", url.Values{"query": {logql}})
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialWebsocket(c.httpClient(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(chan Entry, 100)
+	done := make(chan struct{})
+
+	// The connection is closed when ctx is done or the reader stops, e.g. on a read error.
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	go func() {
+		defer close(entries)
+		defer close(done)
+
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var resp tailResponse
+			if err := json.Unmarshal(msg, &resp); err != nil {
+				continue
+			}
+
+			for _, s := range resp.Streams {
+				for _, v := range s.Values {
+					e, err := decodeStreamValue(v)
+					if err != nil {
+						continue
+					}
+					e.Level = s.Stream["level"]
+					e.Labels = s.Stream
+
+					select {
+					case entries <- e:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return entries, nil
+}
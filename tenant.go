@@ -0,0 +1,52 @@
+package lokilogger
+
+// TenantLabel is the reserved label routing an entry to a tenant, following the convention of
+// Promtail. It is removed from the stream labels and sent as the X-Scope-OrgID header of the
+// push instead, overriding Config.TenantID. Middlewares may set it or Entry.Tenant directly.
+const TenantLabel = "__tenant_id__"
+
+// WithTenant returns a child logger routing its entries to the tenant, e.g. per customer of a
+// multi-tenant gateway. The entries of each tenant are pushed in separate requests.
+func (l *LokiLogger) WithTenant(tenant string) *Child {
+	return l.With(map[string]string{TenantLabel: tenant})
+}
+
+// WithTenant returns a child logger like c routing its entries to the tenant.
+func (c *Child) WithTenant(tenant string) *Child {
+	return c.With(map[string]string{TenantLabel: tenant})
+}
+
+// extractTenant moves the TenantLabel of the entry into Entry.Tenant.
+func extractTenant(e Entry) Entry {
+	tenant, ok := e.Labels[TenantLabel]
+	if !ok {
+		return e
+	}
+
+	// The labels map is shared by the entries of a child logger.
+	labels := make(map[string]string, len(e.Labels)-1)
+	for k, v := range e.Labels {
+		if k != TenantLabel {
+			labels[k] = v
+		}
+	}
+	e.Labels = labels
+	if e.Tenant == "" {
+		e.Tenant = tenant
+	}
+
+	return e
+}
+
+// streamsTenant returns the tenant of streams of a single tenant.
+func streamsTenant(streams []Stream) string {
+	if len(streams) == 0 {
+		return ""
+	}
+	return streams[0].Tenant
+}
+
+// splitTenants groups the streams by tenant, so that every group is pushed with its own X-Scope-OrgID.
+func splitTenants(streams []Stream) [][]Stream {
+	return splitStreams(streams, func(s Stream) string { return s.Tenant })
+}
@@ -0,0 +1,157 @@
+package lokilogger
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// TimestampPolicy defines which time the entries carry.
+type TimestampPolicy int
+
+const (
+	// TimestampEvent keeps the time parsed from written lines or set by the caller.
+	TimestampEvent TimestampPolicy = iota
+	// TimestampArrival sets the time the logger received the entry.
+	TimestampArrival
+)
+
+// originalTimeKey is the structured metadata field keeping the timestamp of an entry clamped by MaxTimestampAge.
+const originalTimeKey = "original_time"
+
+// defaultTimeLayouts are the timestamp layouts recognized at the start of written lines: the standard
+// logger's with log.Ldate and log.Ltime, either of them alone, optionally with microseconds, and RFC 3339
+// with or without fractional seconds.
+var defaultTimeLayouts = []string{"2006/01/02 15:04:05", time.RFC3339, "2006/01/02", "15:04:05"}
+
+// cutTimestamp splits the timestamp written at the start of the line off it. Timestamps without a
+// zone are in Config.TimeLocation, see resolveTime.
+func cutTimestamp(cfg *Config, line string) (t time.Time, rest string, ok bool) {
+	layouts := cfg.TimeLayouts
+	if len(layouts) == 0 {
+		// All default layouts start with a digit.
+		if line == "" || line[0] < '0' || line[0] > '9' {
+			return time.Time{}, line, false
+		}
+		layouts = defaultTimeLayouts
+	}
+	loc := cfg.TimeLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	for _, layout := range layouts {
+		// The timestamp ends at one of the first spaces of the line, as many as the layout may span, e.g.
+		// "Jan _2" matches both "Jan  2" and "Jan 12". Fractional seconds are accepted even if the layout has none.
+		end := 0
+		for range strings.Count(layout, " ") + strings.Count(layout, "_") + 1 {
+			i := strings.IndexByte(line[end+1:], ' ')
+			if i < 0 {
+				end = len(line)
+			} else {
+				end += i + 1
+			}
+
+			if t, err := time.ParseInLocation(layout, line[:end], loc); err == nil {
+				return resolveTime(cfg, layout, t), strings.TrimPrefix(line[end:], " "), true
+			}
+			if end == len(line) {
+				break
+			}
+		}
+	}
+
+	return time.Time{}, line, false
+}
+
+// resolveTime completes the time parsed with the layout: a time without a day, e.g. of log.Ltime alone, is
+// on the current day and one without a year in the current year. Without Config.TimeLocation a time without
+// a zone is in UTC, unless only its reading in the local zone is close to now, as for the standard logger
+// without log.LUTC, so that lines are parsed right whatever flags the application set.
+func resolveTime(cfg *Config, layout string, t time.Time) time.Time {
+	// A date alone, e.g. of log.Ldate, is no more precise than the arrival of a line written today.
+	if !strings.Contains(layout, "04") {
+		for _, loc := range []*time.Location{t.Location(), time.Local} {
+			if y, m, d := time.Now().In(loc).Date(); t.Year() == y && t.Month() == m && t.Day() == d {
+				return time.Now()
+			}
+		}
+	}
+
+	in := func(loc *time.Location) time.Time {
+		now := time.Now().In(loc)
+		switch {
+		case !strings.Contains(layout, "2"):
+			// Every layout of a day of the month contains 2 ("2", "02", "_2").
+			return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+		case t.Year() == 0:
+			return time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+	}
+
+	if cfg.TimeLocation != nil || hasZone(layout) {
+		return in(t.Location())
+	}
+
+	utc := in(time.UTC)
+	if time.Local != time.UTC && !nearNow(utc) {
+		if local := in(time.Local); nearNow(local) {
+			return local
+		}
+	}
+	return utc
+}
+
+// hasZone reports whether the layout has a time zone element.
+func hasZone(layout string) bool {
+	return strings.Contains(layout, "Z07") || strings.Contains(layout, "-07") || strings.Contains(layout, "MST")
+}
+
+// nearNow reports whether t is within a minute of the current time.
+func nearNow(t time.Time) bool {
+	d := time.Since(t)
+	return d > -time.Minute && d < time.Minute
+}
+
+// clampTimestamps returns the streams with timestamps before floor set to floor, keeping the
+// original one in structured metadata so that Loki accepts them after a long outage. The
+// streams are copied when clamped, as they may be pushed to other sinks concurrently.
+func clampTimestamps(streams []Stream, floor time.Time) []Stream {
+	var clamped []Stream
+	for i, s := range streams {
+		var entries []Entry
+		for j, e := range s.Entries {
+			if !e.Time.Before(floor) {
+				continue
+			}
+			if entries == nil {
+				entries = slices.Clone(s.Entries)
+			}
+
+			md := make(map[string]string, len(e.Metadata)+1)
+			for k, v := range e.Metadata {
+				md[k] = v
+			}
+			// A batch clamped by an earlier attempt keeps its first original time.
+			if _, ok := md[originalTimeKey]; !ok {
+				md[originalTimeKey] = e.Time.UTC().Format(time.RFC3339Nano)
+			}
+			entries[j].Metadata = md
+			entries[j].Time = floor
+		}
+
+		if entries == nil {
+			continue
+		}
+		if clamped == nil {
+			clamped = slices.Clone(streams)
+		}
+		clamped[i].Entries = entries
+	}
+
+	if clamped == nil {
+		return streams
+	}
+	return clamped
+}
@@ -0,0 +1,68 @@
+package lokilogger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultAccessTokenRefresh is the interval AccessTokenFile is re-read at by default.
+const defaultAccessTokenRefresh = time.Minute
+
+// fileTokenSource reads the token from a file, re-reading it every interval and after
+// the token was rejected, e.g. when a projected service account token rotates.
+type fileTokenSource struct {
+	path     string
+	interval time.Duration
+
+	mu     sync.Mutex
+	cached string
+	readAt time.Time
+}
+
+func (s *fileTokenSource) invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached == token {
+		s.readAt = time.Time{}
+	}
+}
+
+func (s *fileTokenSource) token(context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != "" && time.Since(s.readAt) < s.interval {
+		return s.cached, nil
+	}
+
+	token, err := readTokenFile(s.path)
+	if err != nil {
+		if s.cached != "" {
+			// Keep using the previous token while the file is being replaced.
+			return s.cached, nil
+		}
+		return "", err
+	}
+
+	s.cached, s.readAt = token, time.Now()
+	return token, nil
+}
+
+// readTokenFile returns the token stored in the file without surrounding whitespace.
+func readTokenFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read access token: %w", err)
+	}
+
+	token := string(bytes.TrimSpace(b))
+	if token == "" {
+		return "", fmt.Errorf("read access token: %s is empty", path)
+	}
+	return token, nil
+}
@@ -0,0 +1,131 @@
+package lokilogger
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ProtocolVictoriaLogs pushes JSON lines to the VictoriaLogs ingestion API (/insert/jsonline).
+const ProtocolVictoriaLogs Protocol = "victorialogs"
+
+const victoriaLogsPushPath = "/insert/jsonline"
+
+// VictoriaLogsSink pushes streams to the JSON lines ingestion API of VictoriaLogs. Every entry becomes
+// a line with the _time and _msg fields, the stream labels including the level, which are declared as
+// stream fields, and the structured metadata as ordinary fields.
+type VictoriaLogsSink struct {
+	URL         string // Ingestion endpoint, e.g. http://victorialogs:9428/insert/jsonline.
+	AccessToken string // Bearer token, e.g. for vmauth.
+	// TenantID selects the tenant as "AccountID" or "AccountID:ProjectID". Stream tenants override it.
+	TenantID string
+	Client   *http.Client
+}
+
+// Push implements Sink. Streams of different tenants are pushed in separate requests.
+func (s *VictoriaLogsSink) Push(ctx context.Context, streams []Stream) error {
+	for _, group := range splitTenants(streams) {
+		if err := s.push(ctx, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// push sends streams of a single tenant in one request.
+func (s *VictoriaLogsSink) push(ctx context.Context, streams []Stream) error {
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	encodeVictoriaLogs(buf, streams)
+	size := buf.Len()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		bufPool.Put(buf)
+		return err
+	}
+	q := u.Query()
+	q.Set("_stream_fields", strings.Join(streamFields(streams), ","))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), newPooledBody(buf))
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = int64(size)
+	req.Header.Set("Content-Type", "application/stream+json")
+
+	if s.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.AccessToken)
+	}
+
+	if tenant := cmp.Or(streamsTenant(streams), s.TenantID); tenant != "" {
+		account, project, _ := strings.Cut(tenant, ":")
+		req.Header.Set("AccountID", account)
+		if project != "" {
+			req.Header.Set("ProjectID", project)
+		}
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	return checkResponse(resp)
+}
+
+// streamFields returns the sorted names of the stream labels.
+func streamFields(streams []Stream) []string {
+	names := make(map[string]struct{})
+	for _, s := range streams {
+		for k := range s.Labels {
+			names[k] = struct{}{}
+		}
+	}
+	return slices.Sorted(maps.Keys(names))
+}
+
+// encodeVictoriaLogs writes the entries as JSON lines for the VictoriaLogs ingestion API:
+//
+//	{"_time":"2024-01-02T15:04:05.123456789Z","_msg":"line","level":"info","service_name":"api","key":"value"}
+//
+// Stream labels take precedence over structured metadata of the same name.
+func encodeVictoriaLogs(buf *bytes.Buffer, streams []Stream) {
+	for _, s := range streams {
+		for _, e := range s.Entries {
+			buf.WriteString(`{"_time":"`)
+			buf.Write(e.Time.UTC().AppendFormat(buf.AvailableBuffer(), time.RFC3339Nano))
+			buf.WriteString(`","_msg":`)
+			writeJSONString(buf, e.Line)
+			for _, k := range sortedKeys(s.Labels) {
+				buf.WriteByte(',')
+				writeJSONString(buf, k)
+				buf.WriteByte(':')
+				writeJSONString(buf, s.Labels[k])
+			}
+			for _, k := range sortedKeys(e.Metadata) {
+				if _, ok := s.Labels[k]; ok || k == "_time" || k == "_msg" {
+					continue
+				}
+				buf.WriteByte(',')
+				writeJSONString(buf, k)
+				buf.WriteByte(':')
+				writeJSONString(buf, e.Metadata[k])
+			}
+			buf.WriteString("}\n")
+		}
+	}
+}
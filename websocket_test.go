@@ -0,0 +1,18 @@
+package lokilogger
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestReadFrameRejectsOversizedLength(t *testing.T) {
+	// A binary frame announcing 2^62 bytes.
+	frame := []byte{0x82, 127, 0x40, 0, 0, 0, 0, 0, 0, 0}
+	c := &wsConn{r: bufio.NewReader(bytes.NewReader(frame))}
+
+	if _, err := c.ReadMessage(); !errors.Is(err, errWsMessageTooLarge) {
+		t.Errorf("ReadMessage: %v, want errWsMessageTooLarge", err)
+	}
+}
@@ -0,0 +1,321 @@
+package lokilogger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML used by configuration files: block
+// mappings and sequences, flow sequences and mappings of scalars, quoted and
+// plain scalars and comments. The result consists of map[string]any, []any,
+// string, int64, float64, bool and nil values.
+func parseYAML(data []byte) (any, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(string(data), "\n") {
+		text := stripYAMLComment(strings.TrimRight(raw, " \t\r"))
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", i+1)
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+
+	if len(lines) == 0 {
+		return map[string]any{}, nil
+	}
+
+	p := &yamlParser{lines: lines}
+	v, err := p.parseBlock(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("yaml: line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+
+	return v, nil
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseBlock parses the mapping or sequence starting at the current line with the given indentation.
+func (p *yamlParser) parseBlock(indent int) (any, error) {
+	if isYAMLSeqItem(p.lines[p.pos].text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseSequence(indent int) (any, error) {
+	seq := []any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || !isYAMLSeqItem(line.text) {
+			break
+		}
+
+		item := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		if item == "" {
+			p.pos++
+			v, err := p.parseNested(indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+
+		// A mapping starting on the item line, e.g. "- key: value".
+		if _, _, ok := splitYAMLKey(item); ok {
+			childIndent := line.indent + len(line.text) - len(item)
+			p.lines[p.pos] = yamlLine{num: line.num, indent: childIndent, text: item}
+			v, err := p.parseMapping(childIndent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+
+		v, err := parseYAMLScalar(item, line.num)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, v)
+		p.pos++
+	}
+
+	return seq, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (any, error) {
+	m := map[string]any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("yaml: line %d: unexpected indentation", line.num)
+		}
+		if isYAMLSeqItem(line.text) {
+			return nil, fmt.Errorf("yaml: line %d: unexpected sequence item in mapping", line.num)
+		}
+
+		key, value, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("yaml: line %d: expected key: value", line.num)
+		}
+		if _, exists := m[key]; exists {
+			return nil, fmt.Errorf("yaml: line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+
+		if value != "" {
+			v, err := parseYAMLScalar(value, line.num)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			continue
+		}
+
+		// A sequence may be indented at the same level as its key.
+		if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSeqItem(p.lines[p.pos].text) {
+			v, err := p.parseSequence(indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			continue
+		}
+
+		v, err := p.parseNested(indent)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+
+	return m, nil
+}
+
+// parseNested parses the block indented deeper than indent, or returns nil if there is none.
+func (p *yamlParser) parseNested(indent int) (any, error) {
+	if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+		return nil, nil
+	}
+	return p.parseBlock(p.lines[p.pos].indent)
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits "key: value" at the first colon outside quotes followed by a space or the end of line.
+func splitYAMLKey(text string) (string, string, bool) {
+	if text == "" || text[0] == '[' || text[0] == '{' {
+		return "", "", false
+	}
+
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			key := strings.TrimSpace(text[:i])
+			if uq, err := unquoteYAML(key); err == nil {
+				key = uq
+			}
+			return key, strings.TrimSpace(text[i+1:]), key != ""
+		}
+	}
+
+	return "", "", false
+}
+
+// stripYAMLComment removes a trailing comment outside quotes.
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return strings.TrimRight(text[:i], " \t")
+		}
+	}
+	return text
+}
+
+// parseYAMLScalar parses a plain, quoted or flow scalar.
+func parseYAMLScalar(text string, line int) (any, error) {
+	switch {
+	case text[0] == '"' || text[0] == '\'':
+		s, err := unquoteYAML(text)
+		if err != nil {
+			return nil, fmt.Errorf("yaml: line %d: %w", line, err)
+		}
+		return s, nil
+	case text[0] == '[':
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("yaml: line %d: unterminated flow sequence", line)
+		}
+		seq := []any{}
+		for _, item := range splitYAMLFlow(text[1 : len(text)-1]) {
+			v, err := parseYAMLScalar(item, line)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+		}
+		return seq, nil
+	case text[0] == '{':
+		if !strings.HasSuffix(text, "}") {
+			return nil, fmt.Errorf("yaml: line %d: unterminated flow mapping", line)
+		}
+		m := map[string]any{}
+		for _, item := range splitYAMLFlow(text[1 : len(text)-1]) {
+			k, v, ok := splitYAMLKey(item)
+			if !ok {
+				return nil, fmt.Errorf("yaml: line %d: expected key: value in flow mapping", line)
+			}
+			if v == "" {
+				m[k] = nil
+				continue
+			}
+			val, err := parseYAMLScalar(v, line)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = val
+		}
+		return m, nil
+	}
+
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+
+	return text, nil
+}
+
+// splitYAMLFlow splits the items of a flow collection at commas outside quotes.
+func splitYAMLFlow(text string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, text[start:i])
+			start = i + 1
+		}
+	}
+	items = append(items, text[start:])
+
+	out := items[:0]
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// unquoteYAML removes single or double quotes from a scalar.
+func unquoteYAML(text string) (string, error) {
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		return strconv.Unquote(text)
+	}
+	if text != "" && (text[0] == '"' || text[0] == '\'') {
+		return "", fmt.Errorf("unterminated quoted string %s", text)
+	}
+	return text, nil
+}